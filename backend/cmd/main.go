@@ -1,70 +1,277 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"event_management/backend/config"
+	"event_management/backend/cors"
 	"event_management/backend/database"
 	"event_management/backend/handlers"
 	"event_management/backend/handlers/auth"
+	"event_management/backend/logging"
+	"event_management/backend/mail"
+	"event_management/backend/metrics"
+	"event_management/backend/tracing"
+	"event_management/backend/utils"
 
 	"github.com/gorilla/mux"
 )
 
 func main() {
-	fmt.Println("Starting the server...")
-	database.InitDB()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "promote" {
+		runPromote(os.Args[2:])
+		return
+	}
+
+	cfg, args, err := config.Load("server", os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	if len(args) > 0 {
+		log.Fatalf("Unknown command %q", args[0])
+	}
+	if err := logging.Setup(os.Stdout, cfg.Log.Level, cfg.Log.Format); err != nil {
+		log.Fatalf("Error configuring logging: %v", err)
+	}
+	slog.Info("Starting the server", "config", cfg)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Error configuring tracing: %v", err)
+	}
+
+	if err := utils.LoadSigningKeys(cfg.JWT.KeysFile, string(cfg.JWT.Secret)); err != nil {
+		log.Fatalf("Error loading JWT signing keys: %v", err)
+	}
+
+	if err := utils.ConfigurePasswordPolicy(cfg.Password.MinLength, cfg.Password.BcryptCost, cfg.Password.DenylistFile); err != nil {
+		log.Fatalf("Error configuring password policy: %v", err)
+	}
+
+	sender, err := newMailSender(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Error configuring mail sender: %v", err)
+	}
+	mail.DefaultSender = sender
+
+	if err := configureCookies(cfg.Cookies); err != nil {
+		log.Fatalf("Error configuring auth cookies: %v", err)
+	}
+
+	auth.ImpersonationReadOnly = cfg.Impersonation.ReadOnly
+	auth.FrontendURL = cfg.Server.FrontendURL
+
+	database.InitDB(cfg.Database)
+	metrics.RegisterDB(database.DB.DB, cfg.Database.Name)
+	sqlStore := database.NewSQLStore(database.DB)
+	srv := handlers.NewServer(sqlStore, sqlStore, sqlStore)
+	authSrv := auth.NewServer(sqlStore)
+
+	if cfg.OIDC.IssuerURL != "" {
+		if err := configureOIDC(authSrv, cfg.OIDC); err != nil {
+			log.Fatalf("Error configuring OIDC login: %v", err)
+		}
+	}
 
 	router := mux.NewRouter()
+	router.Use(logging.CaptureRoute, cors.Middleware(cfg.CORS, router))
+
+	router.HandleFunc("/healthz", handlers.HealthzHandler).Methods("GET")
+	router.HandleFunc("/readyz", handlers.ReadyzHandler).Methods("GET")
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	can := func(permission string, h http.HandlerFunc) http.Handler {
+		return authSrv.RequirePermission(permission)(h)
+	}
+	session := func(h http.HandlerFunc) http.Handler {
+		return auth.RequireSession(h)
+	}
 
-	router.HandleFunc("/signup", auth.SignupHandler).Methods("POST", "OPTIONS")
-	router.HandleFunc("/login", auth.LoginHandler).Methods("POST", "OPTIONS")
-	router.HandleFunc("/validate_token", auth.ValidateTokenHandler).Methods("GET", "OPTIONS")
-	router.HandleFunc("/logout", auth.LogoutHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/signup", authSrv.SignupHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/signup/invitation", authSrv.InvitationSignupHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/login", authSrv.LoginHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/validate_token", authSrv.ValidateTokenHandler).Methods("GET", "OPTIONS")
+	router.HandleFunc("/login/2fa", authSrv.LoginTwoFactorHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/login/2fa/enroll", authSrv.LoginTwoFactorEnrollHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/logout", authSrv.LogoutHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/token/refresh", authSrv.RefreshTokenHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/password/forgot", authSrv.ForgotPasswordHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/password/reset", authSrv.ResetPasswordHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/verify-email", authSrv.VerifyEmailHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/verify-email/resend", authSrv.ResendVerificationHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/auth/oidc/login", authSrv.OIDCLoginHandler).Methods("GET", "OPTIONS")
+	router.HandleFunc("/auth/oidc/callback", authSrv.OIDCCallbackHandler).Methods("GET", "OPTIONS")
+	router.HandleFunc("/impersonation/end", authSrv.EndImpersonationHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/.well-known/jwks.json", auth.JWKSHandler).Methods("GET", "OPTIONS")
 
 	adminRouter := router.PathPrefix("/admin").Subrouter()
-	adminRouter.Use(auth.JWTMiddleware)
-	adminRouter.HandleFunc("/users", handlers.GetAllUsersHandler).Methods("GET", "OPTIONS")
-	adminRouter.HandleFunc("/users/deactivate", handlers.DeactivateUserHandler).Methods("POST", "OPTIONS")
+	adminRouter.Use(authSrv.JWTMiddleware)
+	adminRouter.Handle("/users", can("user:list", srv.GetAllUsersHandler)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/users/deactivate", can("user:deactivate", srv.DeactivateUserHandler)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/users/roles", can("user:manage_roles", srv.GrantUserRoleHandler)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/users/roles", can("user:manage_roles", srv.RevokeUserRoleHandler)).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/users/{id:[0-9]+}/sessions", can("session:manage", authSrv.GetUserSessionsHandler)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/users/{id:[0-9]+}/sessions", can("session:manage", authSrv.RevokeAllUserSessionsHandler)).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/users/{id:[0-9]+}/sessions/{sid}", can("session:manage", authSrv.RevokeUserSessionHandler)).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/users/{id:[0-9]+}/impersonate", can("user:impersonate", authSrv.StartImpersonationHandler)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/impersonations", can("user:impersonate", authSrv.GetImpersonationsHandler)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/impersonations/{id:[0-9]+}/requests", can("user:impersonate", authSrv.GetImpersonationRequestsHandler)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/invitations", can("invitation:manage", authSrv.GetInvitationsHandler)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/invitations", can("invitation:manage", authSrv.CreateInvitationHandler)).Methods("POST", "OPTIONS")
+	adminRouter.Handle("/invitations/{id:[0-9]+}", can("invitation:manage", authSrv.RevokeInvitationHandler)).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/invitations/{id:[0-9]+}/redemptions", can("invitation:manage", authSrv.GetInvitationRedemptionsHandler)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/lockouts", can("lockout:manage", authSrv.GetLockoutsHandler)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/lockouts/{email}", can("lockout:manage", authSrv.ClearLockoutHandler)).Methods("DELETE", "OPTIONS")
+	adminRouter.Handle("/permissions", can("permission:manage", authSrv.GetPermissionsHandler)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/roles/{name}/permissions", can("permission:manage", authSrv.GetRolePermissionsHandler)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/roles/{name}/permissions", can("permission:manage", authSrv.SetRolePermissionsHandler)).Methods("PUT", "OPTIONS")
+	adminRouter.Handle("/roles/2fa", can("mfa_policy:manage", authSrv.GetTwoFactorPoliciesHandler)).Methods("GET", "OPTIONS")
+	adminRouter.Handle("/roles/{name}/2fa", can("mfa_policy:manage", authSrv.SetTwoFactorPolicyHandler)).Methods("PUT", "OPTIONS")
 
 	organiserRouter := router.PathPrefix("/organiser").Subrouter()
-	organiserRouter.Use(auth.JWTMiddleware)
-	organiserRouter.HandleFunc("/events", handlers.GetOrganizerEventsHandler).Methods("GET", "OPTIONS")
-	organiserRouter.HandleFunc("/events/{id:[0-9]+}/registrations", handlers.GetEventRegistrationsHandler).Methods("GET", "OPTIONS")
-	organiserRouter.HandleFunc("/events", handlers.CreateEventHandler).Methods("POST", "OPTIONS")
-	organiserRouter.HandleFunc("/events/{id:[0-9]+}", handlers.UpdateEventHandler).Methods("PUT", "OPTIONS")
-	organiserRouter.HandleFunc("/events/{id:[0-9]+}", handlers.CancelEventHandler).Methods("DELETE", "OPTIONS")
+	organiserRouter.Use(authSrv.JWTMiddleware)
+	organiserRouter.Handle("/events", can("event:view_own", srv.GetOrganizerEventsHandler)).Methods("GET", "OPTIONS")
+	organiserRouter.Handle("/events/{id:[0-9]+}/registrations", can("event:view_registrations", srv.GetEventRegistrationsHandler)).Methods("GET", "OPTIONS")
+	organiserRouter.Handle("/events", can("event:create", srv.CreateEventHandler)).Methods("POST", "OPTIONS")
+	organiserRouter.Handle("/events/{id:[0-9]+}", can("event:update", srv.UpdateEventHandler)).Methods("PUT", "OPTIONS")
+	organiserRouter.Handle("/events/{id:[0-9]+}", can("event:cancel", srv.CancelEventHandler)).Methods("DELETE", "OPTIONS")
 
 	userRouter := router.PathPrefix("").Subrouter()
-	userRouter.Use(auth.JWTMiddleware)
-	userRouter.HandleFunc("/events", handlers.GetEventsHandler).Methods("GET", "OPTIONS")
-	userRouter.HandleFunc("/events/{id:[0-9]+}/register", handlers.RegisterForEventHandler).Methods("POST", "OPTIONS")
-	userRouter.HandleFunc("/registrations/{id:[0-9]+}", handlers.CancelRegistrationHandler).Methods("DELETE", "OPTIONS")
-	userRouter.HandleFunc("/user/profile", handlers.GetUserProfileHandler).Methods("GET", "OPTIONS")
-	userRouter.HandleFunc("/user/profile", handlers.UpdateUserProfileHandler).Methods("PUT", "OPTIONS")
-	userRouter.HandleFunc("/user/registrations", handlers.GetUserRegistrationsHandler).Methods("GET", "OPTIONS")
-
-	cors := func(h http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			w.Header().Set("Access-Control-Max-Age", "86400")
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-			h.ServeHTTP(w, r)
-		})
-	}
+	userRouter.Use(authSrv.JWTMiddleware)
+	userRouter.Handle("/events", can("event:list", srv.GetEventsHandler)).Methods("GET", "OPTIONS")
+	userRouter.Handle("/events/{id:[0-9]+}/register", can("event:register", srv.RegisterForEventHandler)).Methods("POST", "OPTIONS")
+	userRouter.Handle("/registrations/{id:[0-9]+}", can("registration:cancel", srv.CancelRegistrationHandler)).Methods("DELETE", "OPTIONS")
+	userRouter.Handle("/user/profile", can("profile:view", srv.GetUserProfileHandler)).Methods("GET", "OPTIONS")
+	userRouter.Handle("/user/profile", can("profile:update", srv.UpdateUserProfileHandler)).Methods("PUT", "OPTIONS")
+	userRouter.Handle("/user/registrations", can("registration:view_own", srv.GetUserRegistrationsHandler)).Methods("GET", "OPTIONS")
+	userRouter.Handle("/user/2fa/enroll", session(authSrv.EnrollTwoFactorHandler)).Methods("POST", "OPTIONS")
+	userRouter.Handle("/user/2fa/confirm", session(authSrv.ConfirmTwoFactorHandler)).Methods("POST", "OPTIONS")
+	userRouter.Handle("/user/2fa/disable", session(authSrv.DisableTwoFactorHandler)).Methods("POST", "OPTIONS")
+	userRouter.Handle("/user/2fa/recovery-codes", session(authSrv.RegenerateRecoveryCodesHandler)).Methods("POST", "OPTIONS")
+	userRouter.Handle("/user/password", session(authSrv.ChangePasswordHandler)).Methods("POST", "OPTIONS")
+	userRouter.Handle("/user/account/export", session(srv.ExportAccountHandler)).Methods("GET", "OPTIONS")
+	userRouter.Handle("/user/account", session(srv.DeleteAccountHandler)).Methods("DELETE", "OPTIONS")
+	userRouter.Handle("/user/sessions", session(authSrv.GetSessionsHandler)).Methods("GET", "OPTIONS")
+	userRouter.Handle("/user/sessions/{sid}", session(authSrv.RevokeSessionHandler)).Methods("DELETE", "OPTIONS")
+	userRouter.Handle("/user/api-keys", session(authSrv.GetAPIKeysHandler)).Methods("GET", "OPTIONS")
+	userRouter.Handle("/user/api-keys", session(authSrv.CreateAPIKeyHandler)).Methods("POST", "OPTIONS")
+	userRouter.Handle("/user/api-keys/{id:[0-9]+}", session(authSrv.RevokeAPIKeyHandler)).Methods("DELETE", "OPTIONS")
+	userRouter.Handle("/user/identities", session(authSrv.LinkIdentityHandler)).Methods("POST", "OPTIONS")
 
 	server := &http.Server{
-		Addr:    ":8080",
-		Handler: cors(router),
+		Addr:              cfg.Server.Addr,
+		Handler:           logging.Middleware(tracing.Middleware(metrics.Middleware(router))),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	stop, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	go func() {
+		slog.Info("Server running", "addr", cfg.Server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+
+	<-stop.Done()
+	cancel()
+	slog.Info("Shutting down, draining in-flight requests")
+	handlers.MarkShuttingDown()
+
+	ctx, cancelShutdown := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("Error during shutdown", "error", err)
+	}
+	if err := database.DB.Close(); err != nil {
+		slog.Error("Error closing database", "error", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
 	}
+	slog.Info("Server stopped")
+}
 
-	log.Println("Server running on http://localhost:8080")
-	log.Fatal(server.ListenAndServe())
+func newMailSender(cfg config.MailConfig) (mail.Sender, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return &mail.SMTPSender{
+			Addr:     cfg.SMTPAddr,
+			Username: cfg.SMTPUsername,
+			Password: string(cfg.SMTPPassword),
+			From:     cfg.From,
+		}, nil
+	case "outbox":
+		return mail.NewOutboxSender(cfg.OutboxDir, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+func configureCookies(cfg config.CookieConfig) error {
+	sameSite, err := auth.ParseSameSite(cfg.SameSite)
+	if err != nil {
+		return err
+	}
+	return auth.ConfigureCookies(auth.CookieConfig{
+		Enabled:  cfg.Enabled,
+		Secure:   cfg.Secure,
+		SameSite: sameSite,
+		Domain:   cfg.Domain,
+	})
+}
+
+func configureOIDC(authSrv *auth.Server, oidc config.OIDCConfig) error {
+	mapping, err := auth.ParseRoleMapping(oidc.RoleMapping)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cfg := auth.OIDCConfig{
+		IssuerURL:    oidc.IssuerURL,
+		ClientID:     oidc.ClientID,
+		ClientSecret: string(oidc.ClientSecret),
+		RedirectURL:  oidc.RedirectURL,
+		AuthURL:      oidc.AuthURL,
+		Scopes:       oidc.Scopes,
+		GroupsClaim:  oidc.GroupsClaim,
+		RoleMapping:  mapping,
+		AutoCreate:   oidc.AutoCreate,
+	}
+
+	// The identity provider may still be starting, so keep retrying discovery
+	// until the timeout.
+	for {
+		err := authSrv.ConfigureOIDC(ctx, cfg)
+		if err == nil {
+			return nil
+		}
+		slog.Warn("OIDC provider not ready", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(2 * time.Second):
+		}
+	}
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"event_management/backend/config"
+	"event_management/backend/database"
+)
+
+const migrateUsage = `usage:
+  migrate [flags] up            apply all pending migrations
+  migrate [flags] down [n]      revert the last n migrations (default 1)
+  migrate [flags] status        list migrations and when they were applied
+  migrate create [-dir d] name  add empty up/down pairs for every dialect`
+
+func runMigrate(args []string) {
+	if len(args) > 0 && args[0] == "create" {
+		createMigration(args[1:])
+		return
+	}
+
+	cfg, args, err := config.Load("migrate", args)
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	database.Connect(cfg.Database)
+	defer database.DB.Close()
+
+	switch args[0] {
+	case "up":
+		ran, err := database.MigrateUp()
+		for _, mig := range ran {
+			log.Printf("Applied %04d_%s", mig.Version, mig.Name)
+		}
+		if err != nil {
+			log.Fatalf("Error applying migrations: %v", err)
+		}
+		if len(ran) == 0 {
+			log.Println("Schema is already up to date.")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatalf("Invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := database.MigrateDown(steps)
+		for _, mig := range reverted {
+			log.Printf("Reverted %04d_%s", mig.Version, mig.Name)
+		}
+		if err != nil {
+			log.Fatalf("Error reverting migrations: %v", err)
+		}
+		if len(reverted) == 0 {
+			log.Println("No migrations to revert.")
+		}
+	case "status":
+		statuses, err := database.GetMigrationStatus()
+		if err != nil {
+			log.Fatalf("Error reading migration status: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.Applied() {
+				appliedAt = s.AppliedAt
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		w.Flush()
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
+
+func createMigration(args []string) {
+	fs := flag.NewFlagSet("migrate create", flag.ExitOnError)
+	dir := fs.String("dir", "database/migrations", "source directory of the migrations")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal(migrateUsage)
+	}
+
+	paths, err := database.CreateMigration(*dir, fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Error creating migration: %v", err)
+	}
+	for _, path := range paths {
+		log.Printf("Created %s", path)
+	}
+}
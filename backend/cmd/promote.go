@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"event_management/backend/config"
+	"event_management/backend/database"
+	"event_management/backend/store"
+)
+
+const promoteUsage = `usage:
+  promote [flags] email [role]  grant a role (default admin) to an existing account
+
+Roles are otherwise only granted by admins, so use this to create the first
+one: sign up as usual, then run e.g.
+  docker compose run --rm migrate ./main promote you@example.com`
+
+func runPromote(args []string) {
+	cfg, args, err := config.Load("promote", args)
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	if len(args) < 1 || len(args) > 2 {
+		log.Fatal(promoteUsage)
+	}
+	email, role := args[0], "admin"
+	if len(args) == 2 {
+		role = args[1]
+	}
+
+	database.Connect(cfg.Database)
+	defer database.DB.Close()
+	if err := database.CheckSchema(); err != nil {
+		log.Fatalf("Error checking database schema: %v", err)
+	}
+
+	err = database.NewSQLStore(database.DB).GrantRole(context.Background(), email, role)
+	switch {
+	case errors.Is(err, store.ErrUserNotFound):
+		log.Fatalf("No active account with email %s; sign up first", email)
+	case errors.Is(err, store.ErrInvalidRole):
+		log.Fatalf("Unknown role %q", role)
+	case err != nil:
+		log.Fatalf("Error granting role: %v", err)
+	}
+	log.Printf("Granted %s to %s.", role, email)
+}
@@ -0,0 +1,363 @@
+// Package config holds the server's settings. Values come from built-in
+// defaults, an optional YAML or TOML file, environment variables and command
+// line flags, with later sources overriding earlier ones.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Secret is a string that prints as a mask, so passwords and keys do not end
+// up in logs when a config value is formatted.
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "********"
+}
+
+type Config struct {
+	Server        ServerConfig        `yaml:"server" toml:"server"`
+	CORS          CORSConfig          `yaml:"cors" toml:"cors"`
+	Database      DatabaseConfig      `yaml:"database" toml:"database"`
+	JWT           JWTConfig           `yaml:"jwt" toml:"jwt"`
+	Password      PasswordConfig      `yaml:"password" toml:"password"`
+	Mail          MailConfig          `yaml:"mail" toml:"mail"`
+	Cookies       CookieConfig        `yaml:"cookies" toml:"cookies"`
+	OIDC          OIDCConfig          `yaml:"oidc" toml:"oidc"`
+	Impersonation ImpersonationConfig `yaml:"impersonation" toml:"impersonation"`
+	Log           LogConfig           `yaml:"log" toml:"log"`
+	Tracing       TracingConfig       `yaml:"tracing" toml:"tracing"`
+}
+
+type ServerConfig struct {
+	Addr              string        `yaml:"addr" toml:"addr"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// after SIGTERM.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// FrontendURL is used to build links in emails and redirects.
+	FrontendURL string `yaml:"frontend_url" toml:"frontend_url"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins are exact origins such as https://app.example.com, or
+	// https://*.example.com for any subdomain of example.com.
+	AllowedOrigins   []string      `yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" toml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" toml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials" toml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age" toml:"max_age"`
+}
+
+type DatabaseConfig struct {
+	// Driver is mysql, postgres or sqlite.
+	Driver   string `yaml:"driver" toml:"driver"`
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password Secret `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	// SSLMode is passed to PostgreSQL; empty uses the driver's default.
+	SSLMode string `yaml:"sslmode" toml:"sslmode"`
+	// Path is the SQLite database file.
+	Path            string        `yaml:"path" toml:"path"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+}
+
+// DSN returns the connection string for the driver. For MySQL it points at
+// the server only, unless withDB is set, so the database can be created
+// first.
+func (c DatabaseConfig) DSN(withDB bool) string {
+	switch c.Driver {
+	case "postgres":
+		u := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(c.User, string(c.Password)),
+			Host:   net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+			Path:   "/" + c.Name,
+		}
+		if c.SSLMode != "" {
+			u.RawQuery = url.Values{"sslmode": {c.SSLMode}}.Encode()
+		}
+		return u.String()
+	case "sqlite":
+		// Transactions take the write lock up front so that concurrent ones
+		// wait on busy_timeout instead of failing when they upgrade.
+		q := url.Values{
+			"_pragma":      {"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)"},
+			"_txlock":      {"immediate"},
+			"_time_format": {"sqlite"},
+		}
+		return "file:" + c.Path + "?" + q.Encode()
+	}
+
+	cfg := mysql.NewConfig()
+	cfg.User = c.User
+	cfg.Passwd = string(c.Password)
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	if withDB {
+		cfg.DBName = c.Name
+	}
+	return cfg.FormatDSN()
+}
+
+type JWTConfig struct {
+	Secret   Secret `yaml:"secret" toml:"secret"`
+	KeysFile string `yaml:"keys_file" toml:"keys_file"`
+}
+
+type PasswordConfig struct {
+	// Zero keeps the built-in minimum length and bcrypt cost.
+	MinLength    int    `yaml:"min_length" toml:"min_length"`
+	BcryptCost   int    `yaml:"bcrypt_cost" toml:"bcrypt_cost"`
+	DenylistFile string `yaml:"denylist_file" toml:"denylist_file"`
+}
+
+type MailConfig struct {
+	Driver       string `yaml:"driver" toml:"driver"`
+	From         string `yaml:"from" toml:"from"`
+	OutboxDir    string `yaml:"outbox_dir" toml:"outbox_dir"`
+	SMTPAddr     string `yaml:"smtp_addr" toml:"smtp_addr"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username"`
+	SMTPPassword Secret `yaml:"smtp_password" toml:"smtp_password"`
+}
+
+type CookieConfig struct {
+	Enabled  bool   `yaml:"enabled" toml:"enabled"`
+	Secure   bool   `yaml:"secure" toml:"secure"`
+	SameSite string `yaml:"samesite" toml:"samesite"`
+	Domain   string `yaml:"domain" toml:"domain"`
+}
+
+type OIDCConfig struct {
+	IssuerURL    string   `yaml:"issuer_url" toml:"issuer_url"`
+	ClientID     string   `yaml:"client_id" toml:"client_id"`
+	ClientSecret Secret   `yaml:"client_secret" toml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url" toml:"redirect_url"`
+	AuthURL      string   `yaml:"auth_url" toml:"auth_url"`
+	Scopes       []string `yaml:"scopes" toml:"scopes"`
+	GroupsClaim  string   `yaml:"groups_claim" toml:"groups_claim"`
+	RoleMapping  string   `yaml:"role_mapping" toml:"role_mapping"`
+	AutoCreate   bool     `yaml:"auto_create" toml:"auto_create"`
+}
+
+type ImpersonationConfig struct {
+	ReadOnly bool `yaml:"read_only" toml:"read_only"`
+}
+
+type LogConfig struct {
+	// Level is debug, info, warn or error.
+	Level string `yaml:"level" toml:"level"`
+	// Format is json, or text for reading logs in a terminal.
+	Format string `yaml:"format" toml:"format"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, otlp, stdout or file.
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// OTLPEndpoint is the collector's OTLP/HTTP URL. Empty falls back to the
+	// standard OTEL_EXPORTER_OTLP_* environment variables.
+	OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint"`
+	// File receives one JSON span per line with the file exporter.
+	File        string `yaml:"file" toml:"file"`
+	ServiceName string `yaml:"service_name" toml:"service_name"`
+}
+
+// Default returns the settings used for local development with
+// docker-compose.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+			FrontendURL:       "http://localhost:3000",
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   []string{"http://localhost:3000"},
+			AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Requested-With", "X-CSRF-Token", "X-Request-ID", "traceparent", "tracestate"},
+			ExposedHeaders:   []string{"X-Request-ID", "Retry-After"},
+			AllowCredentials: true,
+			MaxAge:           24 * time.Hour,
+		},
+		Database: DatabaseConfig{
+			Driver:          "mysql",
+			Host:            "mysql",
+			Port:            3306,
+			User:            "root",
+			Password:        "1234",
+			Name:            "event_management",
+			Path:            "event_management.db",
+			MaxOpenConns:    100,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Mail: MailConfig{
+			Driver:    "outbox",
+			From:      "no-reply@eventease.local",
+			OutboxDir: "mail_outbox",
+		},
+		Cookies: CookieConfig{
+			Secure:   true,
+			SameSite: "lax",
+		},
+		Impersonation: ImpersonationConfig{
+			ReadOnly: true,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			File:        "traces.jsonl",
+			ServiceName: "event-management-backend",
+		},
+	}
+}
+
+var databaseNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server address is required")
+	check(c.Server.ReadHeaderTimeout > 0 && c.Server.ReadTimeout > 0 && c.Server.WriteTimeout > 0 && c.Server.IdleTimeout > 0,
+		"server timeouts must be positive")
+	check(c.Server.ShutdownTimeout > 0, "shutdown timeout must be positive")
+	check(validURL(c.Server.FrontendURL), "frontend URL %q is not an absolute URL", c.Server.FrontendURL)
+
+	check(len(c.CORS.AllowedOrigins) > 0, "at least one allowed origin is required")
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(validOrigin(origin), "allowed origin %q must be scheme://host[:port], optionally with *. before the host", origin)
+	}
+	check(c.CORS.MaxAge >= 0, "CORS max age cannot be negative")
+
+	switch c.Database.Driver {
+	case "mysql", "postgres":
+		check(c.Database.Host != "", "database host is required")
+		check(c.Database.Port > 0 && c.Database.Port < 65536, "database port %d is out of range", c.Database.Port)
+		check(c.Database.User != "", "database user is required")
+		check(databaseNamePattern.MatchString(c.Database.Name), "database name %q may only contain letters, digits and underscores", c.Database.Name)
+	case "sqlite":
+		check(c.Database.Path != "", "database path is required for the sqlite driver")
+	default:
+		errs = append(errs, fmt.Errorf("unknown database driver %q", c.Database.Driver))
+	}
+	check(c.Database.MaxOpenConns > 0, "database max open connections must be positive")
+	check(c.Database.MaxIdleConns >= 0 && c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database max idle connections must be between 0 and max open connections")
+	check(c.Database.ConnMaxLifetime >= 0, "database connection lifetime cannot be negative")
+
+	check(c.JWT.Secret != "" || c.JWT.KeysFile != "", "a JWT secret or keys file is required")
+
+	check(c.Password.MinLength >= 0, "password minimum length cannot be negative")
+	check(c.Password.BcryptCost >= 0, "bcrypt cost cannot be negative")
+
+	switch c.Mail.Driver {
+	case "outbox":
+		check(c.Mail.OutboxDir != "", "mail outbox directory is required")
+	case "smtp":
+		check(c.Mail.SMTPAddr != "", "SMTP address is required for the smtp mail driver")
+	default:
+		errs = append(errs, fmt.Errorf("unknown mail driver %q", c.Mail.Driver))
+	}
+	check(c.Mail.From != "", "mail sender address is required")
+
+	switch strings.ToLower(c.Cookies.SameSite) {
+	case "", "lax", "strict":
+	case "none":
+		check(c.Cookies.Secure, "SameSite=None cookies must be Secure")
+	default:
+		errs = append(errs, fmt.Errorf("invalid cookie SameSite value %q", c.Cookies.SameSite))
+	}
+
+	if c.OIDC.IssuerURL != "" {
+		check(validURL(c.OIDC.IssuerURL), "OIDC issuer %q is not an absolute URL", c.OIDC.IssuerURL)
+		check(c.OIDC.ClientID != "", "OIDC client ID is required")
+		check(validURL(c.OIDC.RedirectURL), "OIDC redirect URL %q is not an absolute URL", c.OIDC.RedirectURL)
+		check(c.OIDC.AuthURL == "" || validURL(c.OIDC.AuthURL), "OIDC auth URL %q is not an absolute URL", c.OIDC.AuthURL)
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("invalid log level %q", c.Log.Level))
+	}
+	check(c.Log.Format == "json" || c.Log.Format == "text", "log format must be json or text, not %q", c.Log.Format)
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		check(c.Tracing.OTLPEndpoint == "" || validURL(c.Tracing.OTLPEndpoint),
+			"OTLP endpoint %q is not an absolute URL", c.Tracing.OTLPEndpoint)
+	case "file":
+		check(c.Tracing.File != "", "trace file is required for the file exporter")
+	default:
+		errs = append(errs, fmt.Errorf("unknown tracing exporter %q", c.Tracing.Exporter))
+	}
+	check(c.Tracing.ServiceName != "", "tracing service name is required")
+
+	return errors.Join(errs...)
+}
+
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validOrigin accepts an origin without path, query or fragment. A leading
+// "*." in the host allows every subdomain of the rest.
+func validOrigin(s string) bool {
+	u, err := url.Parse(strings.Replace(s, "://*.", "://", 1))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil && !strings.Contains(u.Host, "*")
+}
+
+// String lists every setting by its environment variable name with secrets
+// masked. It is safe to log.
+func (c *Config) String() string {
+	var b strings.Builder
+	for _, s := range c.settings() {
+		fmt.Fprintf(&b, "%s=%s\n", s.env, s.display())
+	}
+	return b.String()
+}
+
+// LogValue logs the same settings as String, as one attribute each.
+func (c *Config) LogValue() slog.Value {
+	settings := c.settings()
+	attrs := make([]slog.Attr, 0, len(settings))
+	for _, s := range settings {
+		attrs = append(attrs, slog.String(s.env, s.display()))
+	}
+	return slog.GroupValue(attrs...)
+}
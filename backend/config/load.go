@@ -0,0 +1,221 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// setting binds one config field to its environment variable. The flag name
+// is derived from it, e.g. DB_HOST becomes -db-host.
+type setting struct {
+	env    string
+	target interface{}
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"HTTP_ADDR", &c.Server.Addr},
+		{"HTTP_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout},
+		{"HTTP_READ_TIMEOUT", &c.Server.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", &c.Server.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", &c.Server.IdleTimeout},
+		{"HTTP_SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout},
+		{"FRONTEND_URL", &c.Server.FrontendURL},
+		{"CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins},
+		{"CORS_ALLOWED_HEADERS", &c.CORS.AllowedHeaders},
+		{"CORS_EXPOSED_HEADERS", &c.CORS.ExposedHeaders},
+		{"CORS_ALLOW_CREDENTIALS", &c.CORS.AllowCredentials},
+		{"CORS_MAX_AGE", &c.CORS.MaxAge},
+		{"DB_DRIVER", &c.Database.Driver},
+		{"DB_HOST", &c.Database.Host},
+		{"DB_PORT", &c.Database.Port},
+		{"DB_USER", &c.Database.User},
+		{"DB_PASSWORD", &c.Database.Password},
+		{"DB_NAME", &c.Database.Name},
+		{"DB_SSLMODE", &c.Database.SSLMode},
+		{"DB_PATH", &c.Database.Path},
+		{"DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns},
+		{"DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns},
+		{"DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime},
+		{"JWT_SECRET", &c.JWT.Secret},
+		{"JWT_KEYS_FILE", &c.JWT.KeysFile},
+		{"PASSWORD_MIN_LENGTH", &c.Password.MinLength},
+		{"BCRYPT_COST", &c.Password.BcryptCost},
+		{"PASSWORD_DENYLIST_FILE", &c.Password.DenylistFile},
+		{"MAIL_DRIVER", &c.Mail.Driver},
+		{"MAIL_FROM", &c.Mail.From},
+		{"MAIL_OUTBOX_DIR", &c.Mail.OutboxDir},
+		{"SMTP_ADDR", &c.Mail.SMTPAddr},
+		{"SMTP_USERNAME", &c.Mail.SMTPUsername},
+		{"SMTP_PASSWORD", &c.Mail.SMTPPassword},
+		{"AUTH_COOKIE_MODE", &c.Cookies.Enabled},
+		{"AUTH_COOKIE_SECURE", &c.Cookies.Secure},
+		{"AUTH_COOKIE_SAMESITE", &c.Cookies.SameSite},
+		{"AUTH_COOKIE_DOMAIN", &c.Cookies.Domain},
+		{"OIDC_ISSUER_URL", &c.OIDC.IssuerURL},
+		{"OIDC_CLIENT_ID", &c.OIDC.ClientID},
+		{"OIDC_CLIENT_SECRET", &c.OIDC.ClientSecret},
+		{"OIDC_REDIRECT_URL", &c.OIDC.RedirectURL},
+		{"OIDC_AUTH_URL", &c.OIDC.AuthURL},
+		{"OIDC_SCOPES", &c.OIDC.Scopes},
+		{"OIDC_GROUPS_CLAIM", &c.OIDC.GroupsClaim},
+		{"OIDC_ROLE_MAPPING", &c.OIDC.RoleMapping},
+		{"OIDC_AUTO_CREATE", &c.OIDC.AutoCreate},
+		{"IMPERSONATION_READ_ONLY", &c.Impersonation.ReadOnly},
+		{"LOG_LEVEL", &c.Log.Level},
+		{"LOG_FORMAT", &c.Log.Format},
+		{"TRACING_EXPORTER", &c.Tracing.Exporter},
+		{"TRACING_OTLP_ENDPOINT", &c.Tracing.OTLPEndpoint},
+		{"TRACING_FILE", &c.Tracing.File},
+		{"TRACING_SERVICE_NAME", &c.Tracing.ServiceName},
+	}
+}
+
+func (s setting) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(s.env), "_", "-")
+}
+
+func (s setting) set(value string) error {
+	switch t := s.target.(type) {
+	case *string:
+		*t = value
+	case *Secret:
+		*t = Secret(value)
+	case *[]string:
+		// Lists are separated by commas or whitespace.
+		*t = strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n'
+		})
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", s.env)
+		}
+		*t = n
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false", s.env)
+		}
+		*t = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a duration such as 5m", s.env)
+		}
+		*t = d
+	default:
+		return fmt.Errorf("%s has an unsupported type %T", s.env, s.target)
+	}
+	return nil
+}
+
+func (s setting) display() string {
+	switch t := s.target.(type) {
+	case *string:
+		return *t
+	case *Secret:
+		return t.String()
+	case *[]string:
+		return strings.Join(*t, ",")
+	case *int:
+		return strconv.Itoa(*t)
+	case *bool:
+		return strconv.FormatBool(*t)
+	case *time.Duration:
+		return t.String()
+	}
+	return ""
+}
+
+// Load builds the configuration from the defaults, the file named by -config
+// or CONFIG_FILE, environment variables and finally the flags in args, then
+// validates it. Arguments left after the flags are returned.
+func Load(name string, args []string) (*Config, []string, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	for _, s := range settings {
+		fs.String(s.flagName(), "", "overrides "+s.env)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value := os.Getenv(s.env); value != "" {
+			if err := s.set(value); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	byFlag := map[string]setting{}
+	for _, s := range settings {
+		byFlag[s.flagName()] = s
+	}
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		if s, ok := byFlag[f.Name]; ok && flagErr == nil {
+			flagErr = s.set(f.Value.String())
+		}
+	})
+	if flagErr != nil {
+		return nil, nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, fs.Args(), nil
+}
+
+// loadFile reads YAML or TOML, chosen by the file extension. Keys missing
+// from the file keep their current values.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err = dec.Decode(c); err == io.EOF {
+			err = nil
+		}
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(data), c)
+		if err == nil {
+			if undecoded := md.Undecoded(); len(undecoded) > 0 {
+				err = fmt.Errorf("unknown key %q", undecoded[0].String())
+			}
+		}
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// Package cors answers cross-origin requests from the configured frontends.
+package cors
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"event_management/backend/config"
+
+	"github.com/gorilla/mux"
+)
+
+// methods are tried against the router to find what a path allows.
+var methods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost,
+	http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+type wildcard struct {
+	scheme string
+	// suffix is the host after the *, including the leading dot.
+	suffix string
+	port   string
+}
+
+type policy struct {
+	router         *mux.Router
+	origins        map[string]bool
+	wildcards      []wildcard
+	allowedHeaders string
+	exposedHeaders string
+	credentials    bool
+	maxAge         string
+}
+
+// Middleware applies the policy to the routes of router; register it with
+// router.Use. Preflight requests are answered with the methods router has for
+// the path, so unknown paths are rejected by the router as usual. The origin
+// is reflected only when it is on the allowlist, and responses vary by Origin
+// so caches keep them apart.
+func Middleware(cfg config.CORSConfig, router *mux.Router) mux.MiddlewareFunc {
+	p := &policy{
+		router:         router,
+		origins:        map[string]bool{},
+		allowedHeaders: strings.Join(cfg.AllowedHeaders, ", "),
+		exposedHeaders: strings.Join(cfg.ExposedHeaders, ", "),
+		credentials:    cfg.AllowCredentials,
+		maxAge:         strconv.Itoa(int(cfg.MaxAge.Seconds())),
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		if scheme, host, ok := strings.Cut(origin, "://*."); ok {
+			u, err := url.Parse(scheme + "://" + host)
+			if err != nil {
+				continue
+			}
+			p.wildcards = append(p.wildcards, wildcard{scheme: u.Scheme, suffix: "." + u.Hostname(), port: u.Port()})
+			continue
+		}
+		p.origins[origin] = true
+	}
+	return p.wrap
+}
+
+func (p *policy) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions && origin != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+			p.preflight(w, r, origin)
+			return
+		}
+
+		if origin != "" && p.allowed(origin) {
+			p.allowOrigin(w, origin)
+			if p.exposedHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", p.exposedHeaders)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (p *policy) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	allowed := p.routeMethods(r)
+
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+	if p.allowed(origin) {
+		p.allowOrigin(w, origin)
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+		if p.allowedHeaders != "" {
+			w.Header().Set("Access-Control-Allow-Headers", p.allowedHeaders)
+		}
+		w.Header().Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *policy) allowOrigin(w http.ResponseWriter, origin string) {
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if p.credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// routeMethods lists the methods some route accepts for the request's path.
+// Several routes may share a path, e.g. GET and DELETE on /user/sessions.
+func (p *policy) routeMethods(r *http.Request) []string {
+	var allowed []string
+	for _, method := range methods {
+		req := r.Clone(r.Context())
+		req.Method = method
+		var match mux.RouteMatch
+		if p.router.Match(req, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+func (p *policy) allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	if len(p.wildcards) == 0 {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Path != "" {
+		return false
+	}
+	host := u.Hostname()
+	for _, wc := range p.wildcards {
+		// The suffix must be preceded by at least one label, so
+		// https://*.example.com does not allow https://example.com.
+		if u.Scheme == wc.scheme && u.Port() == wc.port &&
+			strings.HasSuffix(host, wc.suffix) && len(host) > len(wc.suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"fmt"
+	"time"
+)
+
+// ExportAccount gathers everything stored about a user that they can take
+// with them: the profile, every registration (cancelled ones included) and
+// the events they organised.
+func (s *SQLStore) ExportAccount(ctx context.Context, userID int) (*models.AccountExport, error) {
+	export := models.AccountExport{
+		ExportedAt:      time.Now().UTC().Format(time.RFC3339),
+		Registrations:   []models.AccountRegistration{},
+		OrganisedEvents: []models.Event{},
+	}
+
+	var phone sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id, name, email, phone, verified_at IS NOT NULL, created_at
+		FROM user
+		WHERE user_id = ? AND isalive = 1
+	`, userID).Scan(
+		&export.Profile.ID,
+		&export.Profile.Name,
+		&export.Profile.Email,
+		&phone,
+		&export.Profile.Verified,
+		&export.Profile.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrUserNotFound
+		}
+		return nil, err
+	}
+	export.Profile.Phone = phone.String
+
+	roles, err := getUserRoles(ctx, s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	export.Profile.Roles = make([]string, 0, len(roles))
+	for _, role := range roles {
+		export.Profile.Roles = append(export.Profile.Roles, role.Name)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.registration_id, r.event_id, r.attendee_id, r.registration_date, r.status, r.isalive = 0,
+			e.title, DATE(e.date), e.location
+		FROM registration r
+		JOIN event e ON r.event_id = e.event_id
+		WHERE r.attendee_id = ?
+		ORDER BY r.registration_date DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reg models.AccountRegistration
+		if err := rows.Scan(
+			&reg.ID,
+			&reg.EventID,
+			&reg.UserID,
+			&reg.RegistrationDate,
+			&reg.Status,
+			&reg.Cancelled,
+			&reg.EventName,
+			&reg.EventDate,
+			&reg.EventLocation,
+		); err != nil {
+			return nil, err
+		}
+		export.Registrations = append(export.Registrations, reg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	eventRows, err := s.db.QueryContext(ctx, `
+		SELECT event_id, title, COALESCE(description, ''), DATE(date), location, COALESCE(max_capacity, 0), organiser_id,
+			CASE WHEN isalive = 0 THEN 'cancelled' ELSE 'active' END
+		FROM event
+		WHERE organiser_id = ?
+		ORDER BY date DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer eventRows.Close()
+
+	for eventRows.Next() {
+		var ev models.Event
+		if err := eventRows.Scan(
+			&ev.ID,
+			&ev.Name,
+			&ev.Description,
+			&ev.Date,
+			&ev.Location,
+			&ev.Capacity,
+			&ev.OrganizerID,
+			&ev.Status,
+		); err != nil {
+			return nil, err
+		}
+		export.OrganisedEvents = append(export.OrganisedEvents, ev)
+	}
+
+	return &export, eventRows.Err()
+}
+
+// DeleteAccount anonymises a user instead of deleting the row, so the
+// registrations and events that reference it stay intact. Personal data and
+// every credential are removed, registrations for upcoming events are
+// cancelled and all sessions end. Organisers must cancel their upcoming
+// events first.
+func (s *SQLStore) DeleteAccount(ctx context.Context, userID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	var email string
+	err = tx.QueryRowContext(ctx, "SELECT email FROM user WHERE user_id = ? AND isalive = 1 FOR UPDATE", userID).Scan(&email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrUserNotFound
+		}
+		return err
+	}
+
+	var upcoming int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM event
+		WHERE organiser_id = ? AND isalive = 1 AND date > ?
+	`, userID, now).Scan(&upcoming)
+	if err != nil {
+		return err
+	}
+	if upcoming > 0 {
+		return store.ErrUpcomingEvents
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE registration
+		SET isalive = 0
+		WHERE attendee_id = ? AND isalive = 1
+		  AND event_id IN (SELECT event_id FROM event WHERE date > ?)
+	`, userID, now)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user
+		SET name = 'Deleted user',
+			email = ?,
+			phone = NULL,
+			password = '',
+			isalive = 0,
+			verified_at = NULL,
+			deleted_at = ?,
+			token_version = token_version + 1
+		WHERE user_id = ?
+	`, fmt.Sprintf("deleted-%d@deleted.invalid", userID), now, userID)
+	if err != nil {
+		return err
+	}
+
+	cleanup := []string{
+		"DELETE FROM totp_recovery_code WHERE user_id = ?",
+		"DELETE FROM user_totp WHERE user_id = ?",
+		"DELETE FROM user_identity WHERE user_id = ?",
+		"DELETE FROM api_key WHERE user_id = ?",
+		"DELETE FROM email_verification_token WHERE user_id = ?",
+		"DELETE FROM password_reset_token WHERE user_id = ?",
+		"DELETE FROM refresh_token WHERE user_id = ?",
+		"DELETE FROM user_session WHERE user_id = ?",
+		"UPDATE invitation_redemption SET ip = NULL, user_agent = NULL WHERE user_id = ?",
+	}
+	for _, query := range cleanup {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM login_lockout WHERE email = LOWER(?)", email); err != nil {
+		return err
+	}
+	// Dropping the address would turn a pending invitation into an open one,
+	// so it is revoked as well.
+	_, err = tx.ExecContext(ctx, `
+		UPDATE invitation
+		SET email = NULL, revoked_at = COALESCE(revoked_at, ?)
+		WHERE email = LOWER(?)
+	`, now, email)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
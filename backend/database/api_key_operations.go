@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"strings"
+	"time"
+)
+
+// apiKeyTouchInterval limits how often last_used_at is written for a busy key.
+const apiKeyTouchInterval = time.Minute
+
+func (s *SQLStore) CreateAPIKey(ctx context.Context, userID int, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (int, error) {
+	id, err := s.db.InsertContext(ctx, "key_id", `
+		INSERT INTO api_key (user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, userID, name, prefix, keyHash, strings.Join(scopes, " "), expiresAt, time.Now())
+	return int(id), err
+}
+
+func (s *SQLStore) GetAPIKeys(ctx context.Context, userID int) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT key_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_key
+		WHERE user_id = ?
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key models.APIKey
+		var scopes string
+		var expiresAt, lastUsedAt, revokedAt sql.NullString
+		if err := rows.Scan(
+			&key.ID,
+			&key.Name,
+			&key.Prefix,
+			&scopes,
+			&expiresAt,
+			&lastUsedAt,
+			&revokedAt,
+			&key.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		key.Scopes = strings.Fields(scopes)
+		key.ExpiresAt = expiresAt.String
+		key.LastUsedAt = lastUsedAt.String
+		key.RevokedAt = revokedAt.String
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *SQLStore) RevokeAPIKey(ctx context.Context, userID, keyID int) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE api_key
+		SET revoked_at = ?
+		WHERE key_id = ? AND user_id = ? AND revoked_at IS NULL
+	`, time.Now(), keyID, userID)
+	if err != nil {
+		return err
+	}
+
+	if ra, _ := res.RowsAffected(); ra == 0 {
+		return store.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// RevokeUserAPIKeys revokes every key of a user.
+func (s *SQLStore) RevokeUserAPIKeys(ctx context.Context, userID int) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE api_key
+		SET revoked_at = ?
+		WHERE user_id = ? AND revoked_at IS NULL
+	`, time.Now(), userID)
+	return err
+}
+
+// AuthenticateAPIKey looks up an active key by its hash and returns the owner
+// with their roles. Last-used time is recorded at most once a minute.
+func (s *SQLStore) AuthenticateAPIKey(ctx context.Context, keyHash string) (*models.APIKeyOwner, error) {
+	now := time.Now()
+
+	var owner models.APIKeyOwner
+	var scopes string
+	var phone sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT k.key_id, k.scopes, u.user_id, u.name, u.email, u.phone, u.token_version, u.verified_at IS NOT NULL
+		FROM api_key k
+		JOIN user u ON k.user_id = u.user_id
+		WHERE k.key_hash = ?
+		  AND k.revoked_at IS NULL
+		  AND (k.expires_at IS NULL OR k.expires_at > ?)
+		  AND u.isalive = 1
+	`, keyHash, now).Scan(
+		&owner.KeyID,
+		&scopes,
+		&owner.User.ID,
+		&owner.User.Name,
+		&owner.User.Email,
+		&phone,
+		&owner.User.TokenVersion,
+		&owner.User.Verified,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	owner.User.Phone = phone.String
+	owner.Scopes = strings.Fields(scopes)
+
+	if err := loadUserRoles(ctx, s.db, &owner.User); err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE api_key
+		SET last_used_at = ?
+		WHERE key_id = ?
+		  AND (last_used_at IS NULL OR last_used_at < ?)
+	`, now, owner.KeyID, now.Add(-apiKeyTouchInterval))
+	if err != nil {
+		return nil, err
+	}
+
+	return &owner, nil
+}
@@ -0,0 +1,279 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// Dialect describes how one SQL engine differs from the MySQL flavour the
+// queries in this package are written in. Pool and Tx rewrite every query
+// before it reaches the driver, so only constructs without a common spelling
+// (upserts, date arithmetic) need to ask the dialect directly.
+type Dialect struct {
+	Name string
+	// driver is the database/sql driver name.
+	driver string
+	// system is the OpenTelemetry db.system.name.
+	system string
+	// timestamp is the column type for a date and time without a zone.
+	timestamp string
+
+	rewritten sync.Map
+}
+
+var dialects = map[string]*Dialect{
+	"mysql":    {Name: "mysql", driver: "mysql", system: "mysql", timestamp: "DATETIME"},
+	"postgres": {Name: "postgres", driver: "pgx", system: "postgresql", timestamp: "TIMESTAMP"},
+	"sqlite":   {Name: "sqlite", driver: "sqlite", system: "sqlite", timestamp: "DATETIME"},
+}
+
+// Dialects lists the supported dialect names.
+func Dialects() []string {
+	return []string{"mysql", "postgres", "sqlite"}
+}
+
+func dialectFor(name string) (*Dialect, error) {
+	d, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database driver %q", name)
+	}
+	return d, nil
+}
+
+var (
+	// userTablePattern finds the user table, which is a reserved word in
+	// PostgreSQL. Column names such as user_id do not match.
+	userTablePattern    = regexp.MustCompile(`\b(FROM|JOIN|UPDATE|INTO|EXISTS|TABLE)(\s+)user\b`)
+	insertIgnorePattern = regexp.MustCompile(`\bINSERT IGNORE INTO\b`)
+	forUpdatePattern    = regexp.MustCompile(`\s+FOR UPDATE\b`)
+	excludedPattern     = regexp.MustCompile(`\bexcluded\.(\w+)`)
+)
+
+// rewrite translates a query for the dialect. The result is cached, since
+// the same few query strings are run over and over.
+func (d *Dialect) rewrite(query string) string {
+	if d.Name == "mysql" {
+		return query
+	}
+	if q, ok := d.rewritten.Load(query); ok {
+		return q.(string)
+	}
+
+	q := query
+	switch d.Name {
+	case "postgres":
+		q = userTablePattern.ReplaceAllString(q, `$1$2"user"`)
+		if insertIgnorePattern.MatchString(q) {
+			q = insertIgnorePattern.ReplaceAllString(q, "INSERT INTO")
+			q = strings.TrimRight(q, " \t\n;") + "\n\t\tON CONFLICT DO NOTHING"
+		}
+		q = numberPlaceholders(q)
+	case "sqlite":
+		q = insertIgnorePattern.ReplaceAllString(q, "INSERT OR IGNORE INTO")
+		// Transactions take the write lock when they begin (see DSN), which
+		// gives the same protection as row locks.
+		q = forUpdatePattern.ReplaceAllString(q, "")
+	}
+
+	d.rewritten.Store(query, q)
+	return q
+}
+
+// numberPlaceholders turns ? placeholders into $1, $2, ... outside of
+// string literals.
+func numberPlaceholders(query string) string {
+	var b strings.Builder
+	n := 0
+	inString := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inString = !inString
+		case r == '?' && !inString:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// args stores booleans as integers. Flag columns are small integers in every
+// dialect so the queries can keep comparing them with 0 and 1.
+func (d *Dialect) args(args []interface{}) []interface{} {
+	var converted []interface{}
+	for i, arg := range args {
+		b, ok := arg.(bool)
+		if !ok {
+			continue
+		}
+		if converted == nil {
+			converted = append([]interface{}(nil), args...)
+		}
+		converted[i] = 0
+		if b {
+			converted[i] = 1
+		}
+	}
+	if converted == nil {
+		return args
+	}
+	return converted
+}
+
+// upsert returns the clause that turns an INSERT into an update of the row
+// with the same key. Assignments refer to the inserted values as
+// excluded.column.
+func (d *Dialect) upsert(key string, assignments ...string) string {
+	set := strings.Join(assignments, ",\n\t\t\t")
+	if d.Name == "mysql" {
+		set = excludedPattern.ReplaceAllString(set, "VALUES($1)")
+		return "ON DUPLICATE KEY UPDATE\n\t\t\t" + set
+	}
+	return "ON CONFLICT (" + key + ") DO UPDATE SET\n\t\t\t" + set
+}
+
+// secondsBetween returns an expression for the whole seconds from one
+// timestamp expression to another.
+func (d *Dialect) secondsBetween(from, to string) string {
+	switch d.Name {
+	case "postgres":
+		return fmt.Sprintf("CAST(EXTRACT(EPOCH FROM (%s - CAST(%s AS TIMESTAMP))) AS BIGINT)", to, from)
+	case "sqlite":
+		return fmt.Sprintf("CAST((julianday(%s) - julianday(%s)) * 86400 AS INTEGER)", to, from)
+	default:
+		return fmt.Sprintf("TIMESTAMPDIFF(SECOND, %s, %s)", from, to)
+	}
+}
+
+// Pool is the connection pool. Its query methods take MySQL-flavoured SQL,
+// rewrite it for the configured dialect and trace each statement.
+type Pool struct {
+	*sql.DB
+	Dialect *Dialect
+}
+
+func (p *Pool) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return p.ExecContext(context.Background(), query, args...)
+}
+
+func (p *Pool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query = p.Dialect.rewrite(query)
+	ctx, span := p.Dialect.startSpan(ctx, query)
+	res, err := p.DB.ExecContext(ctx, query, p.Dialect.args(args)...)
+	endSpan(span, err)
+	return res, err
+}
+
+func (p *Pool) Query(query string, args ...interface{}) (*Rows, error) {
+	return p.QueryContext(context.Background(), query, args...)
+}
+
+func (p *Pool) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	query = p.Dialect.rewrite(query)
+	ctx, span := p.Dialect.startSpan(ctx, query)
+	rows, err := p.DB.QueryContext(ctx, query, p.Dialect.args(args)...)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return &Rows{Rows: rows, span: span}, nil
+}
+
+func (p *Pool) QueryRow(query string, args ...interface{}) *Row {
+	return p.QueryRowContext(context.Background(), query, args...)
+}
+
+func (p *Pool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	rows, err := p.QueryContext(ctx, query, args...)
+	return &Row{rows: rows, err: err}
+}
+
+func (p *Pool) Begin() (*Tx, error) {
+	return p.BeginTx(context.Background(), nil)
+}
+
+func (p *Pool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := p.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, Dialect: p.Dialect}, nil
+}
+
+// InsertContext runs an INSERT and returns the generated value of idColumn.
+func (p *Pool) InsertContext(ctx context.Context, idColumn, query string, args ...interface{}) (int64, error) {
+	return insertID(ctx, p, p.Dialect, idColumn, query, args)
+}
+
+// Tx is a transaction on a Pool, with the same query rewriting.
+type Tx struct {
+	*sql.Tx
+	Dialect *Dialect
+}
+
+func (t *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return t.ExecContext(context.Background(), query, args...)
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query = t.Dialect.rewrite(query)
+	ctx, span := t.Dialect.startSpan(ctx, query)
+	res, err := t.Tx.ExecContext(ctx, query, t.Dialect.args(args)...)
+	endSpan(span, err)
+	return res, err
+}
+
+func (t *Tx) Query(query string, args ...interface{}) (*Rows, error) {
+	return t.QueryContext(context.Background(), query, args...)
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	query = t.Dialect.rewrite(query)
+	ctx, span := t.Dialect.startSpan(ctx, query)
+	rows, err := t.Tx.QueryContext(ctx, query, t.Dialect.args(args)...)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return &Rows{Rows: rows, span: span}, nil
+}
+
+func (t *Tx) QueryRow(query string, args ...interface{}) *Row {
+	return t.QueryRowContext(context.Background(), query, args...)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	rows, err := t.QueryContext(ctx, query, args...)
+	return &Row{rows: rows, err: err}
+}
+
+func (t *Tx) InsertContext(ctx context.Context, idColumn, query string, args ...interface{}) (int64, error) {
+	return insertID(ctx, t, t.Dialect, idColumn, query, args)
+}
+
+// insertID uses RETURNING on PostgreSQL, whose driver has no LastInsertId.
+func insertID(ctx context.Context, q querier, d *Dialect, idColumn, query string, args []interface{}) (int64, error) {
+	var id int64
+	if d.Name == "postgres" {
+		query = strings.TrimRight(query, " \t\n;") + "\n\t\tRETURNING " + idColumn
+		err := q.QueryRowContext(ctx, query, args...).Scan(&id)
+		return id, err
+	}
+
+	res, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
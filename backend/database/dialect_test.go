@@ -0,0 +1,77 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNumberPlaceholders(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"WHERE a = ? AND b = ?", "WHERE a = $1 AND b = $2"},
+		{"WHERE a = '?' AND b = ?", "WHERE a = '?' AND b = $1"},
+		{"SET note = 'it''s ?', c = ?", "SET note = 'it''s ?', c = $1"},
+		{"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", "VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)"},
+	}
+	for _, tt := range tests {
+		if got := numberPlaceholders(tt.query); got != tt.want {
+			t.Errorf("numberPlaceholders(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		dialect, query, want string
+	}{
+		{"mysql", "SELECT * FROM user WHERE user_id = ? FOR UPDATE", "SELECT * FROM user WHERE user_id = ? FOR UPDATE"},
+		{"postgres", "SELECT * FROM user WHERE user_id = ? FOR UPDATE", `SELECT * FROM "user" WHERE user_id = $1 FOR UPDATE`},
+		{"sqlite", "SELECT * FROM user WHERE user_id = ? FOR UPDATE", "SELECT * FROM user WHERE user_id = ?"},
+		{"postgres", "SELECT user_id FROM user_role WHERE role_id = ?", "SELECT user_id FROM user_role WHERE role_id = $1"},
+		{"postgres", "UPDATE user SET name = ? WHERE user_id = ?", `UPDATE "user" SET name = $1 WHERE user_id = $2`},
+		{"mysql", "INSERT IGNORE INTO role (name) VALUES (?)", "INSERT IGNORE INTO role (name) VALUES (?)"},
+		{"postgres", "INSERT IGNORE INTO role (name) VALUES (?)", "INSERT INTO role (name) VALUES ($1)\n\t\tON CONFLICT DO NOTHING"},
+		{"sqlite", "INSERT IGNORE INTO role (name) VALUES (?)", "INSERT OR IGNORE INTO role (name) VALUES (?)"},
+	}
+	for _, tt := range tests {
+		d := &Dialect{Name: tt.dialect}
+		if got := d.rewrite(tt.query); got != tt.want {
+			t.Errorf("%s: rewrite(%q) = %q, want %q", tt.dialect, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	tests := []struct {
+		dialect, want string
+	}{
+		{"mysql", "ON DUPLICATE KEY UPDATE email = VALUES(email), last_login_at = VALUES(last_login_at)"},
+		{"postgres", "ON CONFLICT (issuer, subject) DO UPDATE SET email = excluded.email, last_login_at = excluded.last_login_at"},
+		{"sqlite", "ON CONFLICT (issuer, subject) DO UPDATE SET email = excluded.email, last_login_at = excluded.last_login_at"},
+	}
+	for _, tt := range tests {
+		d := &Dialect{Name: tt.dialect}
+		got := d.upsert("issuer, subject", "email = excluded.email", "last_login_at = excluded.last_login_at")
+		if got := strings.Join(strings.Fields(got), " "); got != tt.want {
+			t.Errorf("%s: upsert = %q, want %q", tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestSecondsBetween(t *testing.T) {
+	tests := []struct {
+		dialect, want string
+	}{
+		{"mysql", "TIMESTAMPDIFF(SECOND, ?, locked_until)"},
+		{"postgres", "CAST(EXTRACT(EPOCH FROM (locked_until - CAST(? AS TIMESTAMP))) AS BIGINT)"},
+		{"sqlite", "CAST((julianday(locked_until) - julianday(?)) * 86400 AS INTEGER)"},
+	}
+	for _, tt := range tests {
+		d := &Dialect{Name: tt.dialect}
+		if got := d.secondsBetween("?", "locked_until"); got != tt.want {
+			t.Errorf("%s: secondsBetween = %q, want %q", tt.dialect, got, tt.want)
+		}
+	}
+}
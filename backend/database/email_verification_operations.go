@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"event_management/backend/store"
+	"time"
+)
+
+func (s *SQLStore) CreateEmailVerificationToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE email_verification_token
+		SET used_at = ?
+		WHERE user_id = ?
+		  AND used_at IS NULL
+	`, now, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO email_verification_token (user_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?)
+	`, userID, tokenHash, expiresAt, now)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLStore) VerifyEmail(ctx context.Context, tokenHash string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	var tokenID, userID int
+	err = tx.QueryRowContext(ctx, `
+		SELECT token_id, user_id
+		FROM email_verification_token
+		WHERE token_hash = ?
+		  AND used_at IS NULL
+		  AND expires_at > ?
+		FOR UPDATE
+	`, tokenHash, now).Scan(&tokenID, &userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, store.ErrInvalidVerificationToken
+		}
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE email_verification_token SET used_at = ? WHERE token_id = ?", now, tokenID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user
+		SET verified_at = ?
+		WHERE user_id = ?
+		  AND verified_at IS NULL
+	`, now, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
+}
+
+func (s *SQLStore) GetUnverifiedUserIDByEmail(ctx context.Context, email string) (int, error) {
+	var userID int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id
+		FROM user
+		WHERE email = ?
+		  AND isalive = 1
+		  AND verified_at IS NULL
+	`, email).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, store.ErrUserNotFound
+	}
+	return userID, err
+}
+
+// CountVerificationEmailsSince reports how many verification emails were sent
+// to a user after each of the two cut-off times.
+func (s *SQLStore) CountVerificationEmailsSince(ctx context.Context, userID int, recent, window time.Time) (int, int, error) {
+	var recentCount, windowCount int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN created_at > ? THEN 1 ELSE 0 END), 0), COUNT(*)
+		FROM email_verification_token
+		WHERE user_id = ?
+		  AND created_at > ?
+	`, recent, userID, window).Scan(&recentCount, &windowCount)
+	return recentCount, windowCount, err
+}
+
+func (s *SQLStore) IsUserVerified(ctx context.Context, userID int) (bool, error) {
+	var verified bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT verified_at IS NOT NULL
+		FROM user
+		WHERE user_id = ?
+	`, userID).Scan(&verified)
+	return verified, err
+}
@@ -1,18 +1,19 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"event_management/backend/models"
-	"log"
+	"event_management/backend/store"
 )
 
-func GetEventsByOrganizerID(organizerID int) ([]models.EventWithRegistrationCount, error) {
+func (s *SQLStore) ListEventsByOrganiser(ctx context.Context, organizerID int) ([]models.EventWithRegistrationCount, error) {
 	events := []models.EventWithRegistrationCount{}
 
-	rows, err := DB.Query(`
+	rows, err := s.db.QueryContext(ctx, `
 		SELECT 
-			e.event_id, e.title, e.description, e.date, e.location, e.max_capacity, e.organiser_id, 
+			e.event_id, e.title, e.description, DATE(e.date), e.location, e.max_capacity, e.organiser_id, 
 			CASE WHEN e.isalive = 0 THEN 'cancelled' ELSE 'active' END AS status,
 			COUNT(r.registration_id) as registered_count
 		FROM event e
@@ -50,10 +51,10 @@ func GetEventsByOrganizerID(organizerID int) ([]models.EventWithRegistrationCoun
 	return events, rows.Err()
 }
 
-func GetRegistrationsByEventID(eventID int) ([]models.RegistrationWithUserDetails, error) {
+func (s *SQLStore) ListRegistrationsByEvent(ctx context.Context, eventID int) ([]models.RegistrationWithUserDetails, error) {
 	registrations := []models.RegistrationWithUserDetails{}
 
-	rows, err := DB.Query(`
+	rows, err := s.db.QueryContext(ctx, `
 		SELECT 
 			r.registration_id,
 			r.event_id,
@@ -65,7 +66,6 @@ func GetRegistrationsByEventID(eventID int) ([]models.RegistrationWithUserDetail
 		FROM registration r
 		JOIN user u 
 		  ON r.attendee_id = u.user_id 
-		 AND u.role = 'attendee'
 		WHERE r.event_id = ? 
 		  AND r.isalive = 1
 		ORDER BY r.registration_date DESC
@@ -94,30 +94,29 @@ func GetRegistrationsByEventID(eventID int) ([]models.RegistrationWithUserDetail
 	return registrations, rows.Err()
 }
 
-func IsEventOrganizer(eventID, userID int) (bool, error) {
+func (s *SQLStore) IsEventOrganiser(ctx context.Context, eventID, userID int) (bool, error) {
 	var organiserID int
-	err := DB.QueryRow(`
+	err := s.db.QueryRowContext(ctx, `
 		SELECT organiser_id 
 		FROM event 
 		WHERE event_id = ?
 	`, eventID).Scan(&organiserID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return false, errors.New("event not found")
+			return false, store.ErrEventNotFound
 		}
 		return false, err
 	}
 	return organiserID == userID, nil
 }
 
-func CreateEvent(event models.Event) (models.Event, error) {
+func (s *SQLStore) CreateEvent(ctx context.Context, event models.Event) (models.Event, error) {
 	if event.Status == "" {
 		event.Status = "active"
 	}
 	isActive := event.Status != "cancelled"
 
-	log.Printf("Creating event with capacity: %d", event.Capacity)
-	res, err := DB.Exec(`
+	lastID, err := s.db.InsertContext(ctx, "event_id", `
 		INSERT INTO event 
 			(title, description, date, location, max_capacity, organiser_id, isalive)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
@@ -125,44 +124,38 @@ func CreateEvent(event models.Event) (models.Event, error) {
 	if err != nil {
 		return event, err
 	}
-
-	lastID, err := res.LastInsertId()
-	if err != nil {
-		return event, err
-	}
 	event.ID = int(lastID)
 	return event, nil
 }
 
-func UpdateEvent(event models.Event) (*models.Event, error) {
+func (s *SQLStore) UpdateEvent(ctx context.Context, event models.Event) (models.Event, error) {
 	if event.OrganizerID == 0 {
-		return nil, errors.New("organizer ID is required for update authorization")
+		return event, errors.New("organizer ID is required for update authorization")
 	}
 
-	res, err := DB.Exec(`
+	res, err := s.db.ExecContext(ctx, `
 		UPDATE event
 		SET title = ?, description = ?, date = ?, location = ?, max_capacity = ?
 		WHERE event_id = ? 
 		  AND organiser_id = ?
 	`, event.Name, event.Description, event.Date, event.Location, event.Capacity, event.ID, event.OrganizerID)
 	if err != nil {
-		log.Printf("Error updating event %d: %v", event.ID, err)
-		return nil, errors.New("failed to update event")
+		return event, err
 	}
 
 	ra, err := res.RowsAffected()
 	if err != nil {
-		return nil, errors.New("failed to confirm event update")
+		return event, err
 	}
 	if ra == 0 {
-		return nil, errors.New("no permission or event not found")
+		return event, store.ErrEventNotFound
 	}
 
-	return &event, nil
+	return event, nil
 }
 
-func CancelEvent(eventID int) error {
-	_, err := DB.Exec(`
+func (s *SQLStore) CancelEvent(ctx context.Context, eventID int) error {
+	_, err := s.db.ExecContext(ctx, `
 		UPDATE event 
 		SET isalive = 0 
 		WHERE event_id = ?
@@ -170,12 +163,12 @@ func CancelEvent(eventID int) error {
 	return err
 }
 
-func GetAllEvents() ([]models.EventWithRegistrationCount, error) {
+func (s *SQLStore) ListEvents(ctx context.Context) ([]models.EventWithRegistrationCount, error) {
 	events := []models.EventWithRegistrationCount{}
 
-	rows, err := DB.Query(`
+	rows, err := s.db.QueryContext(ctx, `
 		SELECT 
-			e.event_id, e.title, e.description, e.date, e.location, e.max_capacity, e.organiser_id,
+			e.event_id, e.title, e.description, DATE(e.date), e.location, e.max_capacity, e.organiser_id,
 			CASE WHEN e.isalive = 0 THEN 'cancelled' ELSE 'active' END AS status,
 			COUNT(r.registration_id) as registered_count
 		FROM event e
@@ -212,11 +205,11 @@ func GetAllEvents() ([]models.EventWithRegistrationCount, error) {
 	return events, rows.Err()
 }
 
-func GetEventByID(eventID int) (models.Event, error) {
+func (s *SQLStore) GetEvent(ctx context.Context, eventID int) (models.Event, error) {
 	var ev models.Event
-	err := DB.QueryRow(`
+	err := s.db.QueryRowContext(ctx, `
 		SELECT 
-			event_id, title, description, date, location, max_capacity, organiser_id,
+			event_id, title, description, DATE(date), location, max_capacity, organiser_id,
 			CASE WHEN isalive = 0 THEN 'cancelled' ELSE 'active' END AS status
 		FROM event
 		WHERE event_id = ? 
@@ -233,16 +226,16 @@ func GetEventByID(eventID int) (models.Event, error) {
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return ev, errors.New("event not found")
+			return ev, store.ErrEventNotFound
 		}
 		return ev, err
 	}
 	return ev, nil
 }
 
-func IsUserRegisteredForEvent(userID, eventID int) (bool, error) {
+func (s *SQLStore) IsRegistered(ctx context.Context, userID, eventID int) (bool, error) {
 	var count int
-	err := DB.QueryRow(`
+	err := s.db.QueryRowContext(ctx, `
 		SELECT COUNT(*) 
 		FROM registration 
 		WHERE attendee_id = ? 
@@ -252,9 +245,9 @@ func IsUserRegisteredForEvent(userID, eventID int) (bool, error) {
 	return count > 0, err
 }
 
-func CreateRegistration(reg models.Registration) (int, error) {
+func (s *SQLStore) CreateRegistration(ctx context.Context, reg models.Registration) (int, error) {
 	var capacity, registered int
-	err := DB.QueryRow(`
+	err := s.db.QueryRowContext(ctx, `
 		SELECT e.max_capacity, COUNT(r.registration_id)
 		FROM event e
 		LEFT JOIN registration r 
@@ -266,29 +259,25 @@ func CreateRegistration(reg models.Registration) (int, error) {
 	`, reg.EventID).Scan(&capacity, &registered)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return 0, errors.New("event not found")
+			return 0, store.ErrEventNotFound
 		}
 		return 0, err
 	}
 	if registered >= capacity {
-		return 0, errors.New("event is full")
+		return 0, store.ErrEventFull
 	}
 
-	res, err := DB.Exec(`
+	lastID, err := s.db.InsertContext(ctx, "registration_id", `
 		INSERT INTO registration 
 			(event_id, attendee_id, registration_date, status, isalive)
 		VALUES (?, ?, ?, ?, 1)
 	`, reg.EventID, reg.UserID, reg.RegistrationDate, reg.Status)
-	if err != nil {
-		return 0, err
-	}
-	lastID, err := res.LastInsertId()
 	return int(lastID), err
 }
 
-func IsRegistrationOwner(regID, userID int) (bool, error) {
+func (s *SQLStore) IsRegistrationOwner(ctx context.Context, regID, userID int) (bool, error) {
 	var cnt int
-	err := DB.QueryRow(`
+	err := s.db.QueryRowContext(ctx, `
 		SELECT COUNT(*) 
 		FROM registration 
 		WHERE registration_id = ? 
@@ -298,8 +287,8 @@ func IsRegistrationOwner(regID, userID int) (bool, error) {
 	return cnt > 0, err
 }
 
-func CancelRegistration(regID int) error {
-	_, err := DB.Exec(`
+func (s *SQLStore) CancelRegistration(ctx context.Context, regID int) error {
+	_, err := s.db.ExecContext(ctx, `
 		UPDATE registration 
 		SET isalive = 0 
 		WHERE registration_id = ?
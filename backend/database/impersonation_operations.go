@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"time"
+)
+
+func (s *SQLStore) CreateImpersonation(ctx context.Context, adminID, userID int, reason, jti, ip string, expiresAt time.Time) (int, error) {
+	id, err := s.db.InsertContext(ctx, "impersonation_id", `
+		INSERT INTO impersonation (admin_id, user_id, reason, jti, ip, started_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, adminID, userID, truncate(reason, 255), jti, truncate(ip, 45), time.Now(), expiresAt)
+	return int(id), err
+}
+
+// IsImpersonationActive reports whether an impersonation token may still be
+// used: it has not been ended and the admin behind it is still active.
+func (s *SQLStore) IsImpersonationActive(ctx context.Context, jti string, adminID int) (bool, error) {
+	var active bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT i.ended_at IS NULL AND u.isalive = 1
+		FROM impersonation i
+		JOIN user u ON i.admin_id = u.user_id
+		WHERE i.jti = ? AND i.admin_id = ?
+	`, jti, adminID).Scan(&active)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return active, err
+}
+
+func (s *SQLStore) EndImpersonation(ctx context.Context, jti string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE impersonation
+		SET ended_at = ?
+		WHERE jti = ? AND ended_at IS NULL
+	`, time.Now(), jti)
+	if err != nil {
+		return err
+	}
+
+	if ra, _ := res.RowsAffected(); ra == 0 {
+		return store.ErrImpersonationNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) LogImpersonationRequest(ctx context.Context, jti, method, path string, status int) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO impersonation_request (impersonation_id, method, path, status, created_at)
+		SELECT impersonation_id, ?, ?, ?, ?
+		FROM impersonation
+		WHERE jti = ?
+	`, method, truncate(path, 255), status, time.Now(), jti)
+	return err
+}
+
+func (s *SQLStore) GetImpersonations(ctx context.Context) ([]models.Impersonation, error) {
+	impersonations := []models.Impersonation{}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT i.impersonation_id, i.admin_id, a.email, i.user_id, u.email, i.reason,
+			COALESCE(i.ip, ''), i.started_at, i.expires_at, i.ended_at
+		FROM impersonation i
+		JOIN user a ON i.admin_id = a.user_id
+		JOIN user u ON i.user_id = u.user_id
+		ORDER BY i.started_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var imp models.Impersonation
+		var endedAt sql.NullString
+		if err := rows.Scan(
+			&imp.ID,
+			&imp.AdminID,
+			&imp.AdminEmail,
+			&imp.UserID,
+			&imp.UserEmail,
+			&imp.Reason,
+			&imp.IP,
+			&imp.StartedAt,
+			&imp.ExpiresAt,
+			&endedAt,
+		); err != nil {
+			return nil, err
+		}
+		imp.EndedAt = endedAt.String
+		impersonations = append(impersonations, imp)
+	}
+
+	return impersonations, rows.Err()
+}
+
+func (s *SQLStore) GetImpersonationRequests(ctx context.Context, impersonationID int) ([]models.ImpersonationRequest, error) {
+	requests := []models.ImpersonationRequest{}
+
+	var exists int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM impersonation WHERE impersonation_id = ?", impersonationID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, store.ErrImpersonationNotFound
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT request_id, method, path, status, created_at
+		FROM impersonation_request
+		WHERE impersonation_id = ?
+		ORDER BY request_id
+	`, impersonationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var req models.ImpersonationRequest
+		if err := rows.Scan(&req.ID, &req.Method, &req.Path, &req.Status, &req.CreatedAt); err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+
+	return requests, rows.Err()
+}
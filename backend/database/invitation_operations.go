@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"strings"
+	"time"
+)
+
+func (s *SQLStore) CreateInvitation(ctx context.Context, role, email string, maxUses int, expiresAt time.Time, createdBy int) (int, error) {
+	var roleID int
+	err := s.db.QueryRowContext(ctx, "SELECT role_id FROM role WHERE name = ?", role).Scan(&roleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, store.ErrRoleNotFound
+		}
+		return 0, err
+	}
+
+	var boundEmail interface{}
+	if email != "" {
+		boundEmail = strings.ToLower(email)
+	}
+
+	id, err := s.db.InsertContext(ctx, "invitation_id", `
+		INSERT INTO invitation (role_id, email, max_uses, expires_at, created_by)
+		VALUES (?, ?, ?, ?, ?)
+	`, roleID, boundEmail, maxUses, expiresAt, createdBy)
+	return int(id), err
+}
+
+func (s *SQLStore) GetInvitations(ctx context.Context) ([]models.Invitation, error) {
+	invitations := []models.Invitation{}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT i.invitation_id, r.name, i.email, i.max_uses, i.use_count,
+			i.expires_at, i.revoked_at, i.created_by, i.created_at
+		FROM invitation i
+		JOIN role r ON i.role_id = r.role_id
+		ORDER BY i.created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var inv models.Invitation
+		var email, revokedAt sql.NullString
+		if err := rows.Scan(
+			&inv.ID,
+			&inv.Role,
+			&email,
+			&inv.MaxUses,
+			&inv.UseCount,
+			&inv.ExpiresAt,
+			&revokedAt,
+			&inv.CreatedBy,
+			&inv.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		inv.Email = email.String
+		inv.RevokedAt = revokedAt.String
+		invitations = append(invitations, inv)
+	}
+
+	return invitations, rows.Err()
+}
+
+func (s *SQLStore) RevokeInvitation(ctx context.Context, invitationID int) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE invitation
+		SET revoked_at = ?
+		WHERE invitation_id = ?
+		  AND revoked_at IS NULL
+	`, time.Now(), invitationID)
+	if err != nil {
+		return err
+	}
+	if ra, _ := res.RowsAffected(); ra == 0 {
+		return store.ErrInvitationNotFound
+	}
+	return nil
+}
+
+// RedeemInvitation creates the invited account with the invitation's role and
+// records the redemption, all in one transaction so a single-use invitation
+// cannot be redeemed twice concurrently.
+func (s *SQLStore) RedeemInvitation(ctx context.Context, invitationID int, user models.User, hashedPassword []byte, ip, userAgent string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var role string
+	var email sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT r.name, i.email
+		FROM invitation i
+		JOIN role r ON i.role_id = r.role_id
+		WHERE i.invitation_id = ?
+		  AND i.revoked_at IS NULL
+		  AND i.expires_at > ?
+		  AND i.use_count < i.max_uses
+		FOR UPDATE
+	`, invitationID, time.Now()).Scan(&role, &email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, store.ErrInvalidInvitation
+		}
+		return 0, err
+	}
+
+	if email.Valid && !strings.EqualFold(email.String, user.Email) {
+		return 0, store.ErrInvitationEmailMismatch
+	}
+
+	user.Role = role
+	// The invitation link was mailed to the bound address, which proves it.
+	user.Verified = email.Valid
+
+	userID, err := createUser(ctx, tx, user, hashedPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE invitation SET use_count = use_count + 1 WHERE invitation_id = ?", invitationID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO invitation_redemption (invitation_id, user_id, ip, user_agent)
+		VALUES (?, ?, ?, ?)
+	`, invitationID, userID, ip, truncate(userAgent, 255))
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
+}
+
+func (s *SQLStore) GetInvitationRedemptions(ctx context.Context, invitationID int) ([]models.InvitationRedemption, error) {
+	redemptions := []models.InvitationRedemption{}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ir.redemption_id, ir.invitation_id, ir.user_id, u.email,
+			COALESCE(ir.ip, ''), COALESCE(ir.user_agent, ''), ir.redeemed_at
+		FROM invitation_redemption ir
+		JOIN user u ON ir.user_id = u.user_id
+		WHERE ir.invitation_id = ?
+		ORDER BY ir.redeemed_at DESC
+	`, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var red models.InvitationRedemption
+		if err := rows.Scan(
+			&red.ID,
+			&red.InvitationID,
+			&red.UserID,
+			&red.UserEmail,
+			&red.IP,
+			&red.UserAgent,
+			&red.RedeemedAt,
+		); err != nil {
+			return nil, err
+		}
+		redemptions = append(redemptions, red)
+	}
+
+	return redemptions, rows.Err()
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
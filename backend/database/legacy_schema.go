@@ -0,0 +1,121 @@
+package database
+
+import "fmt"
+
+// legacyColumn is a column added to a table that already existed before
+// versioned migrations. CREATE TABLE IF NOT EXISTS in 0001 leaves such
+// tables as they are, so the column has to be added separately.
+type legacyColumn struct {
+	table, column string
+	// definition is keyed by dialect name.
+	definition map[string]string
+	// backfill runs once, right after the column is added.
+	backfill string
+}
+
+var legacyColumns = []legacyColumn{
+	{
+		table: "role", column: "require_2fa",
+		definition: map[string]string{
+			"mysql":    "BOOLEAN NOT NULL DEFAULT FALSE",
+			"postgres": "SMALLINT NOT NULL DEFAULT 0",
+			"sqlite":   "SMALLINT NOT NULL DEFAULT 0",
+		},
+	},
+	{
+		table: "user", column: "token_version",
+		definition: map[string]string{
+			"mysql":    "INT NOT NULL DEFAULT 0",
+			"postgres": "INT NOT NULL DEFAULT 0",
+			"sqlite":   "INT NOT NULL DEFAULT 0",
+		},
+	},
+	{
+		table: "user", column: "deleted_at",
+		definition: map[string]string{
+			"mysql":    "DATETIME",
+			"postgres": "TIMESTAMP",
+			"sqlite":   "DATETIME",
+		},
+	},
+	{
+		table: "user", column: "verified_at",
+		definition: map[string]string{
+			"mysql":    "DATETIME",
+			"postgres": "TIMESTAMP",
+			"sqlite":   "DATETIME",
+		},
+		// Accounts created before verification existed are trusted as-is.
+		backfill: "UPDATE user SET verified_at = COALESCE(created_at, CURRENT_TIMESTAMP) WHERE verified_at IS NULL",
+	},
+	{
+		table: "refresh_token", column: "session_id",
+		definition: map[string]string{
+			"mysql":    "VARCHAR(64)",
+			"postgres": "VARCHAR(64)",
+			"sqlite":   "VARCHAR(64)",
+		},
+	},
+}
+
+// addLegacyColumns brings tables created before versioned migrations up to
+// the 0001 schema. It runs after the 0001 script and does nothing on a
+// database that script created.
+func addLegacyColumns() error {
+	for _, col := range legacyColumns {
+		exists, err := columnExists(col.table, col.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", col.table, col.column, col.definition[DB.Dialect.Name]))
+		if err != nil {
+			return fmt.Errorf("adding %s.%s: %w", col.table, col.column, err)
+		}
+		if col.backfill != "" {
+			if _, err := DB.Exec(col.backfill); err != nil {
+				return fmt.Errorf("backfilling %s.%s: %w", col.table, col.column, err)
+			}
+		}
+	}
+	return nil
+}
+
+func columnExists(table, column string) (bool, error) {
+	var query string
+	switch DB.Dialect.Name {
+	case "postgres":
+		query = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?"
+	case "sqlite":
+		query = "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?"
+	default:
+		query = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?"
+	}
+
+	var count int
+	if err := DB.QueryRow(query, table, column).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func tableExists(table string) (bool, error) {
+	var query string
+	switch DB.Dialect.Name {
+	case "postgres":
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+	case "sqlite":
+		query = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	default:
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	}
+
+	var count int
+	if err := DB.QueryRow(query, table).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
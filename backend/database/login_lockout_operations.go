@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"event_management/backend/models"
+	"time"
+)
+
+// GetLockoutRemaining returns how long the account is still locked, or zero.
+func (s *SQLStore) GetLockoutRemaining(ctx context.Context, email string) (time.Duration, error) {
+	now := time.Now()
+
+	var seconds int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT `+s.db.Dialect.secondsBetween("?", "locked_until")+`
+		FROM login_lockout
+		WHERE email = ?
+		  AND locked_until > ?
+	`, now, email, now).Scan(&seconds)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return time.Duration(seconds+1) * time.Second, nil
+}
+
+// RecordFailedLogin counts a failed attempt and returns the running total.
+// Failures older than resetAfter no longer count towards a lockout.
+func (s *SQLStore) RecordFailedLogin(ctx context.Context, email string, resetAfter time.Duration) (int, error) {
+	now := time.Now()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO login_lockout (email, failed_attempts, last_failed_at)
+		VALUES (?, 1, ?)
+		`+s.db.Dialect.upsert("email",
+		"failed_attempts = CASE WHEN login_lockout.last_failed_at < ? THEN 1 ELSE login_lockout.failed_attempts + 1 END",
+		"last_failed_at = excluded.last_failed_at",
+	), email, now, now.Add(-resetAfter))
+	if err != nil {
+		return 0, err
+	}
+
+	var attempts int
+	err = s.db.QueryRowContext(ctx, "SELECT failed_attempts FROM login_lockout WHERE email = ?", email).Scan(&attempts)
+	return attempts, err
+}
+
+func (s *SQLStore) LockAccount(ctx context.Context, email string, until time.Time) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE login_lockout SET locked_until = ? WHERE email = ?", until, email)
+	return err
+}
+
+func (s *SQLStore) ClearLockout(ctx context.Context, email string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM login_lockout WHERE email = ?", email)
+	if err != nil {
+		return false, err
+	}
+	ra, err := res.RowsAffected()
+	return ra > 0, err
+}
+
+func (s *SQLStore) GetLoginLockouts(ctx context.Context) ([]models.LoginLockout, error) {
+	lockouts := []models.LoginLockout{}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT email, failed_attempts, last_failed_at, locked_until, locked_until IS NOT NULL AND locked_until > ?
+		FROM login_lockout
+		ORDER BY last_failed_at DESC
+	`, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l models.LoginLockout
+		var lockedUntil sql.NullString
+		if err := rows.Scan(&l.Email, &l.FailedAttempts, &l.LastFailedAt, &lockedUntil, &l.Locked); err != nil {
+			return nil, err
+		}
+		l.LockedUntil = lockedUntil.String
+		lockouts = append(lockouts, l)
+	}
+
+	return lockouts, rows.Err()
+}
@@ -0,0 +1,352 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+var (
+	ErrSchemaBehind = errors.New("database schema is behind")
+	ErrSchemaAhead  = errors.New("database schema is newer than this build")
+)
+
+// upgrades run right after the script of their version, for changes that
+// depend on what the database already contains.
+var upgrades = map[int]func() error{
+	1: addLegacyColumns,
+}
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt string
+}
+
+func (s MigrationStatus) Applied() bool {
+	return s.AppliedAt != ""
+}
+
+// loadMigrations reads the embedded migrations for the connected dialect,
+// sorted by version. Every version needs both an up and a down file.
+func loadMigrations() ([]Migration, error) {
+	dir := "migrations/" + DB.Dialect.Name
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		m := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+
+		data, err := migrationFiles.ReadFile(dir + "/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func ensureMigrationsTable() error {
+	_, err := DB.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at ` + DB.Dialect.timestamp + ` NOT NULL
+		)
+	`)
+	return err
+}
+
+// appliedMigrations reads schema_migrations without changing anything, so
+// it is safe to call from probes. A database without the table has nothing
+// applied yet.
+func appliedMigrations() (map[int]string, error) {
+	exists, err := tableExists("schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return map[int]string{}, nil
+	}
+
+	rows, err := DB.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]string{}
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// GetMigrationStatus lists every known migration and when it was applied.
+func GetMigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, mig := range migrations {
+		statuses = append(statuses, MigrationStatus{
+			Version:   mig.Version,
+			Name:      mig.Name,
+			AppliedAt: applied[mig.Version],
+		})
+	}
+	return statuses, nil
+}
+
+// CheckSchema fails when migrations are pending or the database has been
+// migrated by a newer build. It only reads, so readiness probes can call it.
+func CheckSchema() error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	known := map[int]bool{}
+	pending := 0
+	for _, mig := range migrations {
+		known[mig.Version] = true
+		if _, ok := applied[mig.Version]; !ok {
+			pending++
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("%w: unknown migration %d is applied", ErrSchemaAhead, version)
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d pending migration(s), run 'migrate up'", ErrSchemaBehind, pending)
+	}
+	return nil
+}
+
+// MigrateUp applies every pending migration in order and returns the ones it
+// ran. MySQL commits DDL implicitly, so a failing migration may leave earlier
+// statements applied and has to be repaired by hand. Scripts are written for
+// their dialect and run without rewriting.
+func MigrateUp() ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, mig := range migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if err := execScript(mig.Up); err != nil {
+			return ran, fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		if upgrade := upgrades[mig.Version]; upgrade != nil {
+			if err := upgrade(); err != nil {
+				return ran, fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+		}
+		_, err := DB.Exec(`
+			INSERT INTO schema_migrations (version, name, applied_at)
+			VALUES (?, ?, ?)
+		`, mig.Version, mig.Name, time.Now())
+		if err != nil {
+			return ran, err
+		}
+		ran = append(ran, mig)
+	}
+	return ran, nil
+}
+
+// MigrateDown reverts the latest steps applied migrations, newest first.
+func MigrateDown(steps int) ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		mig := migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if err := execScript(mig.Down); err != nil {
+			return reverted, fmt.Errorf("reverting %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		if _, err := DB.Exec("DELETE FROM schema_migrations WHERE version = ?", mig.Version); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, mig)
+	}
+	return reverted, nil
+}
+
+// CreateMigration writes an empty up/down pair with the next version number
+// into each dialect's directory under dir, which should be the source
+// migrations directory.
+func CreateMigration(dir, name string) ([]string, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name is required")
+	}
+
+	next := 1
+	for _, dialect := range Dialects() {
+		entries, err := os.ReadDir(filepath.Join(dir, dialect))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if m := migrationFilePattern.FindStringSubmatch(entry.Name()); m != nil {
+				if version, _ := strconv.Atoi(m[1]); version >= next {
+					next = version + 1
+				}
+			}
+		}
+	}
+
+	var paths []string
+	for _, dialect := range Dialects() {
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, dialect, fmt.Sprintf("%04d_%s.%s.sql", next, name, direction))
+			if err := os.WriteFile(path, []byte("-- "+direction+" migration for "+name+"\n"), 0o644); err != nil {
+				return paths, err
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+func execScript(script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := DB.DB.Exec(stmt); err != nil {
+			return fmt.Errorf("%w\n%s", err, stmt)
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a script on semicolons outside of quotes and
+// comments, since the driver runs one statement per call.
+func splitStatements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	var quote rune
+	lineComment := false
+
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case lineComment:
+			if r == '\n' {
+				lineComment = false
+				current.WriteRune(r)
+			}
+			continue
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			lineComment = true
+			continue
+		case r == ';':
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	flush()
+	return stmts
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"event_management/backend/config"
+	"event_management/backend/models"
+)
+
+// openTestDB points DB at a new, empty SQLite database for the test.
+func openTestDB(t *testing.T) {
+	t.Helper()
+	Connect(config.DatabaseConfig{
+		Driver:       "sqlite",
+		Path:         filepath.Join(t.TempDir(), "test.db"),
+		MaxOpenConns: 4,
+		MaxIdleConns: 4,
+	})
+	t.Cleanup(func() { DB.Close() })
+}
+
+// migrateTestDB opens a test database with the current schema.
+func migrateTestDB(t *testing.T) {
+	t.Helper()
+	openTestDB(t)
+	if _, err := MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+}
+
+func TestMigrateUpFromBaselineSchema(t *testing.T) {
+	openTestDB(t)
+
+	baseline, err := os.ReadFile("testdata/baseline_sqlite.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := execScript(string(baseline)); err != nil {
+		t.Fatalf("creating baseline schema: %v", err)
+	}
+	_, err = DB.Exec("INSERT INTO user (name, email, phone, password) VALUES ('Old', 'old@example.com', '', 'hash')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = DB.Exec("INSERT INTO user_role (user_id, role_id) SELECT 1, role_id FROM role WHERE name = 'attendee'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckSchema(); err == nil {
+		t.Fatal("CheckSchema passed before migrating")
+	}
+	if _, err := MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if err := CheckSchema(); err != nil {
+		t.Fatalf("CheckSchema after MigrateUp: %v", err)
+	}
+
+	for _, col := range legacyColumns {
+		exists, err := columnExists(col.table, col.column)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Errorf("%s.%s was not added", col.table, col.column)
+		}
+	}
+
+	user, err := NewSQLStore(DB).AuthenticateUser(context.Background(), "old@example.com")
+	if err != nil {
+		t.Fatalf("AuthenticateUser: %v", err)
+	}
+	if !user.Verified {
+		t.Error("existing user was not marked as verified")
+	}
+	if user.TokenVersion != 0 {
+		t.Errorf("token version = %d, want 0", user.TokenVersion)
+	}
+}
+
+func TestMigrateUpOnEmptyDatabase(t *testing.T) {
+	migrateTestDB(t)
+
+	if err := CheckSchema(); err != nil {
+		t.Fatalf("CheckSchema: %v", err)
+	}
+
+	// New accounts must still start unverified.
+	s := NewSQLStore(DB)
+	id, err := s.CreateUser(context.Background(), newTestUser("new@example.com"), []byte("hash"))
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	user, err := s.GetUser(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Verified {
+		t.Error("new user is verified")
+	}
+
+	if _, err := MigrateDown(1); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if _, err := MigrateUp(); err != nil {
+		t.Fatalf("MigrateUp after MigrateDown: %v", err)
+	}
+}
+
+func TestCheckSchemaIsReadOnly(t *testing.T) {
+	openTestDB(t)
+
+	if err := CheckSchema(); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("CheckSchema on an empty database = %v, want ErrSchemaBehind", err)
+	}
+	exists, err := tableExists("schema_migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Error("CheckSchema created schema_migrations")
+	}
+}
+
+func newTestUser(email string) models.User {
+	return models.User{Name: "Test", Email: email, Role: "attendee"}
+}
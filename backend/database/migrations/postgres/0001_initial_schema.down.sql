@@ -0,0 +1,23 @@
+DROP TABLE IF EXISTS impersonation_request;
+DROP TABLE IF EXISTS impersonation;
+DROP TABLE IF EXISTS api_key;
+DROP TABLE IF EXISTS oidc_login_state;
+DROP TABLE IF EXISTS user_identity;
+DROP TABLE IF EXISTS invitation_redemption;
+DROP TABLE IF EXISTS invitation;
+DROP TABLE IF EXISTS login_lockout;
+DROP TABLE IF EXISTS totp_recovery_code;
+DROP TABLE IF EXISTS user_totp;
+DROP TABLE IF EXISTS email_verification_token;
+DROP TABLE IF EXISTS password_reset_token;
+DROP TABLE IF EXISTS revoked_token;
+DROP TABLE IF EXISTS user_session;
+DROP TABLE IF EXISTS refresh_token;
+DROP TABLE IF EXISTS registration;
+DROP TABLE IF EXISTS event;
+DROP TABLE IF EXISTS event_category;
+DROP TABLE IF EXISTS role_permission;
+DROP TABLE IF EXISTS permission;
+DROP TABLE IF EXISTS user_role;
+DROP TABLE IF EXISTS "user";
+DROP TABLE IF EXISTS role;
@@ -0,0 +1,269 @@
+-- Baseline schema for SQLite. It matches the MySQL baseline, with flags
+-- stored as integers and without ON UPDATE CURRENT_TIMESTAMP, which SQLite
+-- has no column option for.
+
+CREATE TABLE IF NOT EXISTS role (
+	role_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(50) UNIQUE NOT NULL,
+	description VARCHAR(255),
+	require_2fa SMALLINT NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT OR IGNORE INTO role (name)
+VALUES ('admin'), ('organiser'), ('attendee');
+
+CREATE TABLE IF NOT EXISTS user (
+	user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(100) NOT NULL,
+	email VARCHAR(100) UNIQUE NOT NULL,
+	phone VARCHAR(20),
+	password VARCHAR(255) NOT NULL,
+	isalive SMALLINT DEFAULT 1,
+	token_version INT NOT NULL DEFAULT 0,
+	verified_at DATETIME,
+	deleted_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_email ON user (email);
+
+CREATE TABLE IF NOT EXISTS user_role (
+	user_id INT NOT NULL,
+	role_id INT NOT NULL,
+	assigned_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, role_id),
+	FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+	FOREIGN KEY (role_id) REFERENCES role(role_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_user_role_user ON user_role (user_id);
+CREATE INDEX IF NOT EXISTS idx_user_role_role ON user_role (role_id);
+
+CREATE TABLE IF NOT EXISTS permission (
+	permission_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(100) UNIQUE NOT NULL,
+	description VARCHAR(255),
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permission (
+	role_id INT NOT NULL,
+	permission_id INT NOT NULL,
+	assigned_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (role_id, permission_id),
+	FOREIGN KEY (role_id) REFERENCES role(role_id) ON DELETE CASCADE,
+	FOREIGN KEY (permission_id) REFERENCES permission(permission_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS event_category (
+	category_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(50) UNIQUE NOT NULL,
+	description TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS event (
+	event_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	organiser_id INT NOT NULL,
+	title VARCHAR(100) NOT NULL,
+	description TEXT,
+	date DATETIME NOT NULL,
+	location VARCHAR(255) NOT NULL,
+	max_capacity INT,
+	category_id INT,
+	isalive SMALLINT DEFAULT 1,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (organiser_id) REFERENCES user(user_id),
+	FOREIGN KEY (category_id) REFERENCES event_category(category_id)
+);
+CREATE INDEX IF NOT EXISTS idx_event_date ON event (date);
+CREATE INDEX IF NOT EXISTS idx_event_organiser ON event (organiser_id);
+
+CREATE TABLE IF NOT EXISTS registration (
+	registration_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_id INT NOT NULL,
+	attendee_id INT NOT NULL,
+	registration_date DATETIME DEFAULT CURRENT_TIMESTAMP,
+	status VARCHAR(50) NOT NULL DEFAULT 'pending',
+	isalive SMALLINT DEFAULT 1,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (event_id) REFERENCES event(event_id),
+	FOREIGN KEY (attendee_id) REFERENCES user(user_id),
+	CONSTRAINT unique_event_attendee UNIQUE (event_id, attendee_id)
+);
+CREATE INDEX IF NOT EXISTS idx_registration_event ON registration (event_id);
+CREATE INDEX IF NOT EXISTS idx_registration_attendee ON registration (attendee_id);
+
+CREATE TABLE IF NOT EXISTS refresh_token (
+	token_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INT NOT NULL,
+	token_hash CHAR(64) UNIQUE NOT NULL,
+	expires_at DATETIME NOT NULL,
+	revoked_at DATETIME,
+	replaced_by INT,
+	session_id VARCHAR(64),
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_token_user ON refresh_token (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_token_session ON refresh_token (session_id);
+
+CREATE TABLE IF NOT EXISTS user_session (
+	session_id VARCHAR(64) PRIMARY KEY,
+	user_id INT NOT NULL,
+	user_agent VARCHAR(255),
+	ip VARCHAR(45),
+	created_at DATETIME NOT NULL,
+	last_seen_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	revoked_at DATETIME,
+	FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_user_session_user ON user_session (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_token (
+	jti VARCHAR(64) PRIMARY KEY,
+	user_id INT NOT NULL,
+	expires_at DATETIME NOT NULL,
+	revoked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_token_expires ON revoked_token (expires_at);
+
+CREATE TABLE IF NOT EXISTS password_reset_token (
+	token_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INT NOT NULL,
+	token_hash CHAR(64) UNIQUE NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_token_user ON password_reset_token (user_id);
+
+CREATE TABLE IF NOT EXISTS email_verification_token (
+	token_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INT NOT NULL,
+	token_hash CHAR(64) UNIQUE NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used_at DATETIME,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_email_verification_token_user ON email_verification_token (user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS user_totp (
+	user_id INT PRIMARY KEY,
+	secret VARCHAR(64) NOT NULL,
+	enabled_at DATETIME,
+	last_used_step BIGINT NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS totp_recovery_code (
+	code_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INT NOT NULL,
+	code_hash CHAR(64) NOT NULL,
+	used_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_totp_recovery_code_user ON totp_recovery_code (user_id);
+
+CREATE TABLE IF NOT EXISTS login_lockout (
+	email VARCHAR(100) PRIMARY KEY,
+	failed_attempts INT NOT NULL DEFAULT 0,
+	last_failed_at DATETIME NOT NULL,
+	locked_until DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS invitation (
+	invitation_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	role_id INT NOT NULL,
+	email VARCHAR(100),
+	max_uses INT NOT NULL DEFAULT 1,
+	use_count INT NOT NULL DEFAULT 0,
+	expires_at DATETIME NOT NULL,
+	revoked_at DATETIME,
+	created_by INT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (role_id) REFERENCES role(role_id),
+	FOREIGN KEY (created_by) REFERENCES user(user_id)
+);
+
+CREATE TABLE IF NOT EXISTS invitation_redemption (
+	redemption_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	invitation_id INT NOT NULL,
+	user_id INT NOT NULL,
+	ip VARCHAR(45),
+	user_agent VARCHAR(255),
+	redeemed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (invitation_id) REFERENCES invitation(invitation_id),
+	FOREIGN KEY (user_id) REFERENCES user(user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_invitation_redemption_invitation ON invitation_redemption (invitation_id);
+
+CREATE TABLE IF NOT EXISTS user_identity (
+	identity_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INT NOT NULL,
+	issuer VARCHAR(255) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	email VARCHAR(100),
+	last_login_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+	CONSTRAINT uq_user_identity_subject UNIQUE (issuer, subject)
+);
+CREATE INDEX IF NOT EXISTS idx_user_identity_user ON user_identity (user_id);
+
+CREATE TABLE IF NOT EXISTS oidc_login_state (
+	state_hash CHAR(64) PRIMARY KEY,
+	nonce VARCHAR(64) NOT NULL,
+	code_verifier VARCHAR(128) NOT NULL,
+	expires_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_key (
+	key_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INT NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	prefix VARCHAR(16) NOT NULL,
+	key_hash CHAR(64) NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	expires_at DATETIME,
+	last_used_at DATETIME,
+	revoked_at DATETIME,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_api_key_user ON api_key (user_id);
+
+CREATE TABLE IF NOT EXISTS impersonation (
+	impersonation_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	admin_id INT NOT NULL,
+	user_id INT NOT NULL,
+	reason VARCHAR(255) NOT NULL,
+	jti VARCHAR(64) NOT NULL UNIQUE,
+	ip VARCHAR(45),
+	started_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	ended_at DATETIME,
+	FOREIGN KEY (admin_id) REFERENCES user(user_id),
+	FOREIGN KEY (user_id) REFERENCES user(user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_impersonation_user ON impersonation (user_id);
+
+CREATE TABLE IF NOT EXISTS impersonation_request (
+	request_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	impersonation_id INT NOT NULL,
+	method VARCHAR(10) NOT NULL,
+	path VARCHAR(255) NOT NULL,
+	status INT NOT NULL,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (impersonation_id) REFERENCES impersonation(impersonation_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_impersonation_request_impersonation ON impersonation_request (impersonation_id);
@@ -0,0 +1,233 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"time"
+)
+
+func (s *SQLStore) SaveOIDCLoginState(ctx context.Context, stateHash, nonce, codeVerifier string, expiresAt time.Time) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM oidc_login_state WHERE expires_at < ?", time.Now()); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO oidc_login_state (state_hash, nonce, code_verifier, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, stateHash, nonce, codeVerifier, expiresAt)
+	return err
+}
+
+// ConsumeOIDCLoginState returns the nonce and PKCE verifier saved for a login
+// attempt and deletes them, so a state value can only complete one login.
+func (s *SQLStore) ConsumeOIDCLoginState(ctx context.Context, stateHash string) (string, string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback()
+
+	var nonce, codeVerifier string
+	var expired bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT nonce, code_verifier, expires_at < ?
+		FROM oidc_login_state
+		WHERE state_hash = ?
+		FOR UPDATE
+	`, time.Now(), stateHash).Scan(&nonce, &codeVerifier, &expired)
+	if err == sql.ErrNoRows {
+		return "", "", store.ErrInvalidLoginState
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM oidc_login_state WHERE state_hash = ?", stateHash); err != nil {
+		return "", "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", "", err
+	}
+
+	if expired {
+		return "", "", store.ErrInvalidLoginState
+	}
+	return nonce, codeVerifier, nil
+}
+
+// GetUserIDByIdentity finds the active user linked to an external identity.
+// It returns store.ErrUserNotFound when the identity has not been linked yet.
+func (s *SQLStore) GetUserIDByIdentity(ctx context.Context, issuer, subject string) (int, error) {
+	var userID int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT u.user_id
+		FROM user_identity i
+		JOIN user u ON i.user_id = u.user_id
+		WHERE i.issuer = ? AND i.subject = ? AND u.isalive = 1
+	`, issuer, subject).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, store.ErrUserNotFound
+	}
+	return userID, err
+}
+
+// LinkIdentity attaches an external identity to a user, or records another
+// login for an identity that is already linked.
+func (s *SQLStore) LinkIdentity(ctx context.Context, userID int, issuer, subject, email string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_identity (user_id, issuer, subject, email, last_login_at)
+		VALUES (?, ?, ?, ?, ?)
+		`+s.db.Dialect.upsert("issuer, subject",
+		"email = excluded.email",
+		"last_login_at = excluded.last_login_at",
+	), userID, issuer, subject, email, time.Now())
+	return err
+}
+
+// AddUserIdentity links an external identity to an existing user at the
+// user's request. It fails with store.ErrIdentityInUse when the identity already
+// belongs to someone else; linking it to the same user again is a no-op.
+func (s *SQLStore) AddUserIdentity(ctx context.Context, userID int, issuer, subject, email string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ownerID int
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id
+		FROM user_identity
+		WHERE issuer = ? AND subject = ?
+		FOR UPDATE
+	`, issuer, subject).Scan(&ownerID)
+	switch {
+	case err == nil && ownerID == userID:
+		return nil
+	case err == nil:
+		return store.ErrIdentityInUse
+	case err != sql.ErrNoRows:
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_identity (user_id, issuer, subject, email)
+		VALUES (?, ?, ?, ?)
+	`, userID, issuer, subject, email)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CreateExternalUser creates an account for someone signing in through the
+// identity provider for the first time and links the identity to it.
+func (s *SQLStore) CreateExternalUser(ctx context.Context, user models.User, hashedPassword []byte, issuer, subject string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	userID, err := createUser(ctx, tx, user, hashedPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_identity (user_id, issuer, subject, email, last_login_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, issuer, subject, user.Email, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
+}
+
+// MarkUserVerified marks the account verified when its address is email,
+// which the identity provider has vouched for.
+func (s *SQLStore) MarkUserVerified(ctx context.Context, userID int, email string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE user
+		SET verified_at = ?
+		WHERE user_id = ? AND email = ? AND verified_at IS NULL
+	`, time.Now(), userID, email)
+	return err
+}
+
+// SyncManagedRoles makes the user's membership of the managed roles match
+// granted: managed roles in granted are added, the others removed. Roles
+// outside managed are left alone, and a removal that would leave the user
+// without any role is skipped. It reports whether a role was removed.
+func (s *SQLStore) SyncManagedRoles(ctx context.Context, userID int, managed, granted []string) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	current := map[string]bool{}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT r.name
+		FROM user_role ur
+		JOIN role r ON ur.role_id = r.role_id
+		WHERE ur.user_id = ?
+		FOR UPDATE
+	`, userID)
+	if err != nil {
+		return false, err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return false, err
+		}
+		current[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	want := map[string]bool{}
+	for _, role := range granted {
+		want[role] = true
+	}
+
+	for _, role := range managed {
+		if want[role] && !current[role] {
+			_, err := tx.ExecContext(ctx, `
+				INSERT IGNORE INTO user_role (user_id, role_id)
+				SELECT ?, role_id FROM role WHERE name = ?
+			`, userID, role)
+			if err != nil {
+				return false, err
+			}
+			current[role] = true
+		}
+	}
+
+	removed := false
+	for _, role := range managed {
+		if want[role] || !current[role] || len(current) <= 1 {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM user_role
+			WHERE user_id = ?
+			  AND role_id = (SELECT role_id FROM role WHERE name = ?)
+		`, userID, role)
+		if err != nil {
+			return false, err
+		}
+		delete(current, role)
+		removed = true
+	}
+
+	return removed, tx.Commit()
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"event_management/backend/models"
+	"event_management/backend/store"
+)
+
+var (
+	datePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	dateTimePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}$`)
+)
+
+// TestOperationsOnSQLite walks through the event and registration operations
+// the handlers use, on a freshly migrated SQLite database.
+func TestOperationsOnSQLite(t *testing.T) {
+	migrateTestDB(t)
+	ctx := context.Background()
+	s := NewSQLStore(DB)
+
+	organiser := newTestUser("organiser@example.com")
+	organiser.Role = "organiser"
+	organiserID, err := s.CreateUser(ctx, organiser, []byte("hash"))
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	attendeeID, err := s.CreateUser(ctx, newTestUser("attendee@example.com"), []byte("hash"))
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	event, err := s.CreateEvent(ctx, models.Event{
+		Name:        "Launch",
+		Description: "Product launch",
+		Date:        "2027-01-01",
+		Location:    "Hall A",
+		Capacity:    1,
+		OrganizerID: organiserID,
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+
+	got, err := s.GetEvent(ctx, event.ID)
+	if err != nil {
+		t.Fatalf("GetEvent: %v", err)
+	}
+	if got.Date != "2027-01-01" {
+		t.Errorf("GetEvent date = %q, want 2027-01-01", got.Date)
+	}
+
+	events, err := s.ListEvents(ctx)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].Date != "2027-01-01" {
+		t.Fatalf("ListEvents = %+v, want one event on 2027-01-01", events)
+	}
+
+	regID, err := s.CreateRegistration(ctx, models.Registration{
+		EventID:          event.ID,
+		UserID:           attendeeID,
+		RegistrationDate: time.Now().Format("2006-01-02 15:04:05"),
+		Status:           "confirmed",
+	})
+	if err != nil {
+		t.Fatalf("CreateRegistration: %v", err)
+	}
+	_, err = s.CreateRegistration(ctx, models.Registration{EventID: event.ID, UserID: organiserID, Status: "confirmed"})
+	if !errors.Is(err, store.ErrEventFull) {
+		t.Errorf("CreateRegistration on a full event = %v, want ErrEventFull", err)
+	}
+
+	registrations, err := s.ListRegistrationsByUser(ctx, attendeeID)
+	if err != nil {
+		t.Fatalf("ListRegistrationsByUser: %v", err)
+	}
+	if len(registrations) != 1 || !dateTimePattern.MatchString(registrations[0].RegistrationDate) {
+		t.Fatalf("ListRegistrationsByUser = %+v, want one registration with a YYYY-MM-DD hh:mm:ss date", registrations)
+	}
+
+	byEvent, err := s.ListRegistrationsByEvent(ctx, event.ID)
+	if err != nil {
+		t.Fatalf("ListRegistrationsByEvent: %v", err)
+	}
+	if len(byEvent) != 1 || byEvent[0].Email != "attendee@example.com" {
+		t.Fatalf("ListRegistrationsByEvent = %+v", byEvent)
+	}
+
+	own, err := s.ListEventsByOrganiser(ctx, organiserID)
+	if err != nil {
+		t.Fatalf("ListEventsByOrganiser: %v", err)
+	}
+	if len(own) != 1 || own[0].RegisteredCount != 1 || !datePattern.MatchString(own[0].Date) {
+		t.Fatalf("ListEventsByOrganiser = %+v", own)
+	}
+
+	if err := s.CancelRegistration(ctx, regID); err != nil {
+		t.Fatalf("CancelRegistration: %v", err)
+	}
+	if registered, err := s.IsRegistered(ctx, attendeeID, event.ID); err != nil || registered {
+		t.Errorf("IsRegistered after cancelling = %v, %v", registered, err)
+	}
+
+	if err := s.CancelEvent(ctx, event.ID); err != nil {
+		t.Fatalf("CancelEvent: %v", err)
+	}
+	if _, err := s.GetEvent(ctx, event.ID); !errors.Is(err, store.ErrEventNotFound) {
+		t.Errorf("GetEvent after cancelling = %v, want ErrEventNotFound", err)
+	}
+
+	// Timestamps written as time.Time and nullable columns read the same way.
+	expiresAt := time.Now().Add(time.Hour)
+	if _, err := s.CreateAPIKey(ctx, attendeeID, "ci", "em_test", "hash", []string{"event:list"}, &expiresAt); err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	keys, err := s.GetAPIKeys(ctx, attendeeID)
+	if err != nil {
+		t.Fatalf("GetAPIKeys: %v", err)
+	}
+	if len(keys) != 1 || !dateTimePattern.MatchString(keys[0].CreatedAt) || !dateTimePattern.MatchString(keys[0].ExpiresAt) || keys[0].RevokedAt != "" {
+		t.Errorf("GetAPIKeys = %+v, want YYYY-MM-DD hh:mm:ss timestamps", keys)
+	}
+}
+
+func TestAPIKeysOutliveRoleChangesButNotPasswordChanges(t *testing.T) {
+	migrateTestDB(t)
+	ctx := context.Background()
+	s := NewSQLStore(DB)
+
+	userID, err := s.CreateUser(ctx, newTestUser("keys@example.com"), []byte("hash"))
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := s.CreateAPIKey(ctx, userID, "ci", "em_test", "hash", []string{"event:list"}, nil); err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+
+	if err := s.GrantRole(ctx, "keys@example.com", "organiser"); err != nil {
+		t.Fatalf("GrantRole: %v", err)
+	}
+	if err := s.RevokeRole(ctx, "keys@example.com", "organiser"); err != nil {
+		t.Fatalf("RevokeRole: %v", err)
+	}
+	if _, err := s.AuthenticateAPIKey(ctx, "hash"); err != nil {
+		t.Fatalf("AuthenticateAPIKey after a role revoke: %v", err)
+	}
+
+	if err := s.ChangePassword(ctx, userID, []byte("new hash")); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if _, err := s.AuthenticateAPIKey(ctx, "hash"); !errors.Is(err, store.ErrInvalidAPIKey) {
+		t.Errorf("AuthenticateAPIKey after a password change = %v, want ErrInvalidAPIKey", err)
+	}
+}
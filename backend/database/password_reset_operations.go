@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"event_management/backend/store"
+	"time"
+)
+
+func (s *SQLStore) GetUserIDByEmail(ctx context.Context, email string) (int, error) {
+	var userID int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id
+		FROM user
+		WHERE email = ? AND isalive = 1
+	`, email).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, store.ErrUserNotFound
+	}
+	return userID, err
+}
+
+// CreatePasswordResetToken stores a new reset token and invalidates any
+// earlier unused ones, so only the most recent email works.
+func (s *SQLStore) CreatePasswordResetToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE password_reset_token
+		SET used_at = ?
+		WHERE user_id = ?
+		  AND used_at IS NULL
+	`, now, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO password_reset_token (user_id, token_hash, expires_at)
+		VALUES (?, ?, ?)
+	`, userID, tokenHash, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetPasswordResetEmail returns the email of the account a usable reset token
+// belongs to, so the new password can be checked against it.
+func (s *SQLStore) GetPasswordResetEmail(ctx context.Context, tokenHash string) (string, error) {
+	var email string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT u.email
+		FROM password_reset_token prt
+		JOIN user u ON prt.user_id = u.user_id
+		WHERE prt.token_hash = ?
+		  AND prt.used_at IS NULL
+		  AND prt.expires_at > ?
+		  AND u.isalive = 1
+	`, tokenHash, time.Now()).Scan(&email)
+	if err == sql.ErrNoRows {
+		return "", store.ErrInvalidResetToken
+	}
+	return email, err
+}
+
+func (s *SQLStore) ResetPassword(ctx context.Context, tokenHash string, hashedPassword []byte) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	var tokenID, userID int
+	err = tx.QueryRowContext(ctx, `
+		SELECT prt.token_id, prt.user_id
+		FROM password_reset_token prt
+		JOIN user u ON prt.user_id = u.user_id
+		WHERE prt.token_hash = ?
+		  AND prt.used_at IS NULL
+		  AND prt.expires_at > ?
+		  AND u.isalive = 1
+		FOR UPDATE
+	`, tokenHash, now).Scan(&tokenID, &userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, store.ErrInvalidResetToken
+		}
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE password_reset_token SET used_at = ? WHERE token_id = ?", now, tokenID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE user SET password = ? WHERE user_id = ?", hashedPassword, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if err := s.RevokeAllUserTokens(ctx, userID); err != nil {
+		return 0, err
+	}
+	return userID, s.RevokeUserAPIKeys(ctx, userID)
+}
+
+// ChangePassword stores a new password, ends every existing session and
+// revokes the user's API keys.
+func (s *SQLStore) ChangePassword(ctx context.Context, userID int, hashedPassword []byte) error {
+	if err := s.UpdatePasswordHash(ctx, userID, hashedPassword); err != nil {
+		return err
+	}
+	if err := s.RevokeAllUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	return s.RevokeUserAPIKeys(ctx, userID)
+}
+
+func (s *SQLStore) UpdatePasswordHash(ctx context.Context, userID int, hashedPassword []byte) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE user SET password = ? WHERE user_id = ? AND isalive = 1", hashedPassword, userID)
+	return err
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"fmt"
+)
+
+// seedPermissions inserts the built-in permissions. Default role mappings are
+// only added the first time a permission appears, so later edits made through
+// the API survive restarts.
+func seedPermissions() error {
+	for _, p := range store.DefaultPermissions {
+		res, err := DB.Exec(`
+			INSERT IGNORE INTO permission (name, description)
+			VALUES (?, ?)
+		`, p.Name, p.Description)
+		if err != nil {
+			return err
+		}
+
+		if ra, _ := res.RowsAffected(); ra == 0 {
+			continue
+		}
+
+		for _, role := range p.Roles {
+			_, err := DB.Exec(`
+				INSERT IGNORE INTO role_permission (role_id, permission_id)
+				SELECT r.role_id, p.permission_id
+				FROM role r, permission p
+				WHERE r.name = ? AND p.name = ?
+			`, role, p.Name)
+			if err != nil {
+				return fmt.Errorf("mapping %s to %s: %w", p.Name, role, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) GetAllPermissions(ctx context.Context) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT permission_id, name, COALESCE(description, '')
+		FROM permission
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Permission
+		if err := rows.Scan(&p.ID, &p.Name, &p.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+
+	return permissions, rows.Err()
+}
+
+// GetRolePermissionMap returns the permission names granted to every role.
+func (s *SQLStore) GetRolePermissionMap(ctx context.Context) (map[string][]string, error) {
+	rolePermissions := map[string][]string{}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.name, p.name
+		FROM role_permission rp
+		JOIN role r ON rp.role_id = r.role_id
+		JOIN permission p ON rp.permission_id = p.permission_id
+		ORDER BY r.name, p.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var role, permission string
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, err
+		}
+		rolePermissions[role] = append(rolePermissions[role], permission)
+	}
+
+	return rolePermissions, rows.Err()
+}
+
+func (s *SQLStore) GetRolePermissions(ctx context.Context, role string) ([]string, error) {
+	var roleID int
+	err := s.db.QueryRowContext(ctx, "SELECT role_id FROM role WHERE name = ?", role).Scan(&roleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRoleNotFound
+		}
+		return nil, err
+	}
+
+	permissions := []string{}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p.name
+		FROM role_permission rp
+		JOIN permission p ON rp.permission_id = p.permission_id
+		WHERE rp.role_id = ?
+		ORDER BY p.name
+	`, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, name)
+	}
+
+	return permissions, rows.Err()
+}
+
+// SetRolePermissions replaces the permissions of a role with the given set.
+func (s *SQLStore) SetRolePermissions(ctx context.Context, role string, permissions []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var roleID int
+	err = tx.QueryRowContext(ctx, "SELECT role_id FROM role WHERE name = ?", role).Scan(&roleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrRoleNotFound
+		}
+		return err
+	}
+
+	permissionIDs := make([]int, 0, len(permissions))
+	for _, name := range permissions {
+		var id int
+		err := tx.QueryRowContext(ctx, "SELECT permission_id FROM permission WHERE name = ?", name).Scan(&id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w: %s", store.ErrUnknownPermission, name)
+			}
+			return err
+		}
+		permissionIDs = append(permissionIDs, id)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM role_permission WHERE role_id = ?", roleID); err != nil {
+		return err
+	}
+
+	for _, id := range permissionIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT IGNORE INTO role_permission (role_id, permission_id)
+			VALUES (?, ?)
+		`, roleID, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
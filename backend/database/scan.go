@@ -0,0 +1,107 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// The MySQL driver returns dates and times as text, e.g. 2027-01-01 for a
+// DATE and 2027-01-01 18:30:00 for a DATETIME, and the API passes that text
+// on. The PostgreSQL and SQLite drivers return time.Time instead, which
+// database/sql would turn into RFC 3339 when scanning into a string. Rows and
+// Row format such values the MySQL way, so every dialect answers alike.
+// Event dates live in a DATETIME column but only carry a day, so queries
+// select them as DATE(date).
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// Scan is sql.Rows.Scan with dates and times normalised for string and
+// sql.NullString destinations.
+func (r *Rows) Scan(dest ...interface{}) error {
+	if r.dateColumns == nil {
+		types, err := r.Rows.ColumnTypes()
+		if err != nil {
+			return err
+		}
+		r.dateColumns = make([]bool, len(types))
+		for i, t := range types {
+			r.dateColumns[i] = strings.EqualFold(t.DatabaseTypeName(), "DATE")
+		}
+	}
+
+	targets := make([]interface{}, len(dest))
+	for i, d := range dest {
+		targets[i] = d
+		date := i < len(r.dateColumns) && r.dateColumns[i]
+		switch d := d.(type) {
+		case *string:
+			targets[i] = &timeText{str: d, date: date}
+		case *sql.NullString:
+			targets[i] = &timeText{null: d, date: date}
+		}
+	}
+	return r.Rows.Scan(targets...)
+}
+
+// timeText scans into a string or sql.NullString, formatting time.Time.
+type timeText struct {
+	str  *string
+	null *sql.NullString
+	date bool
+}
+
+func (t *timeText) Scan(src interface{}) error {
+	var value sql.NullString
+	if ts, ok := src.(time.Time); ok {
+		layout := dateTimeLayout
+		if t.date {
+			layout = dateLayout
+		}
+		value = sql.NullString{String: ts.UTC().Format(layout), Valid: true}
+	} else if err := value.Scan(src); err != nil {
+		return err
+	}
+
+	if t.null != nil {
+		*t.null = value
+		return nil
+	}
+	if !value.Valid {
+		return errors.New("converting NULL to string is unsupported")
+	}
+	*t.str = value.String
+	return nil
+}
+
+// Row is the result of QueryRow. Like sql.Row it reports sql.ErrNoRows from
+// Scan when the query matched nothing.
+type Row struct {
+	rows *Rows
+	err  error
+}
+
+func (r *Row) Err() error {
+	return r.err
+}
+
+func (r *Row) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	return r.rows.Close()
+}
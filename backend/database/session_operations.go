@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"time"
+)
+
+// sessionTouchInterval limits how often last_seen_at is written for a session
+// that is making many requests.
+const sessionTouchInterval = time.Minute
+
+func createSession(ctx context.Context, tx *Tx, userID int, session models.SessionInfo, expiresAt time.Time) error {
+	now := time.Now()
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO user_session (session_id, user_id, user_agent, ip, created_at, last_seen_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, session.ID, userID, truncate(session.UserAgent, 255), truncate(session.IP, 45), now, now, expiresAt)
+	return err
+}
+
+// GetUserSessions lists the sessions of a user that are neither revoked nor
+// expired, most recently used first.
+func (s *SQLStore) GetUserSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	sessions := []models.Session{}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT session_id, COALESCE(user_agent, ''), COALESCE(ip, ''), created_at, last_seen_at, expires_at
+		FROM user_session
+		WHERE user_id = ?
+		  AND revoked_at IS NULL
+		  AND expires_at > ?
+		ORDER BY last_seen_at DESC
+	`, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.UserAgent, &session.IP, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeSession signs a session out. Its refresh tokens stop working at once
+// and its access tokens are rejected by the session check.
+func (s *SQLStore) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE user_session
+		SET revoked_at = ?
+		WHERE session_id = ? AND user_id = ? AND revoked_at IS NULL
+	`, now, sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if ra, _ := res.RowsAffected(); ra == 0 {
+		return store.ErrSessionNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE refresh_token
+		SET revoked_at = ?
+		WHERE session_id = ? AND revoked_at IS NULL
+	`, now, sessionID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLStore) TouchSession(ctx context.Context, sessionID string) error {
+	now := time.Now()
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE user_session
+		SET last_seen_at = ?
+		WHERE session_id = ?
+		  AND last_seen_at < ?
+	`, now, sessionID, now.Add(-sessionTouchInterval))
+	return err
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"event_management/backend/store"
+)
+
+// querier is satisfied by both *Pool and *Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row
+	InsertContext(ctx context.Context, idColumn, query string, args ...interface{}) (int64, error)
+}
+
+// SQLStore implements the store interfaces on top of a connection pool, in
+// any of the supported dialects.
+type SQLStore struct {
+	db *Pool
+}
+
+var (
+	_ store.EventStore        = (*SQLStore)(nil)
+	_ store.RegistrationStore = (*SQLStore)(nil)
+	_ store.UserStore         = (*SQLStore)(nil)
+
+	_ store.CredentialStore    = (*SQLStore)(nil)
+	_ store.LockoutStore       = (*SQLStore)(nil)
+	_ store.SessionStore       = (*SQLStore)(nil)
+	_ store.TwoFactorStore     = (*SQLStore)(nil)
+	_ store.APIKeyStore        = (*SQLStore)(nil)
+	_ store.PermissionStore    = (*SQLStore)(nil)
+	_ store.InvitationStore    = (*SQLStore)(nil)
+	_ store.ImpersonationStore = (*SQLStore)(nil)
+	_ store.IdentityStore      = (*SQLStore)(nil)
+)
+
+func NewSQLStore(db *Pool) *SQLStore {
+	return &SQLStore{db: db}
+}
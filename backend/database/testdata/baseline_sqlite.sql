@@ -0,0 +1,70 @@
+-- The schema InitDB created before versioned migrations, as of the first
+-- release, translated to SQLite.
+
+CREATE TABLE role (
+	role_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(50) UNIQUE NOT NULL,
+	description VARCHAR(255),
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO role (name) VALUES ('admin'), ('organiser'), ('attendee');
+
+CREATE TABLE user (
+	user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(100) NOT NULL,
+	email VARCHAR(100) UNIQUE NOT NULL,
+	phone VARCHAR(20),
+	password VARCHAR(255) NOT NULL,
+	isalive BOOLEAN DEFAULT 1,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_role (
+	user_id INT NOT NULL,
+	role_id INT NOT NULL,
+	assigned_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, role_id),
+	FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+	FOREIGN KEY (role_id) REFERENCES role(role_id) ON DELETE CASCADE
+);
+
+CREATE TABLE event_category (
+	category_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(50) UNIQUE NOT NULL,
+	description TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE event (
+	event_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	organiser_id INT NOT NULL,
+	title VARCHAR(100) NOT NULL,
+	description TEXT,
+	date DATETIME NOT NULL,
+	location VARCHAR(255) NOT NULL,
+	max_capacity INT,
+	category_id INT,
+	isalive BOOLEAN DEFAULT 1,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (organiser_id) REFERENCES user(user_id),
+	FOREIGN KEY (category_id) REFERENCES event_category(category_id)
+);
+
+CREATE TABLE registration (
+	registration_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_id INT NOT NULL,
+	attendee_id INT NOT NULL,
+	registration_date DATETIME DEFAULT CURRENT_TIMESTAMP,
+	status VARCHAR(50) NOT NULL DEFAULT 'pending',
+	isalive BOOLEAN DEFAULT 1,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (event_id) REFERENCES event(event_id),
+	FOREIGN KEY (attendee_id) REFERENCES user(user_id),
+	UNIQUE (event_id, attendee_id)
+);
@@ -0,0 +1,224 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"time"
+)
+
+// CreateRefreshToken starts a new session for a login and stores its first
+// refresh token.
+func (s *SQLStore) CreateRefreshToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time, session models.SessionInfo) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createSession(ctx, tx, userID, session, expiresAt); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO refresh_token (user_id, token_hash, expires_at, session_id)
+		VALUES (?, ?, ?, ?)
+	`, userID, tokenHash, expiresAt, session.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RotateRefreshToken swaps a refresh token for a new one in the same session
+// and returns the owning user and session ID. Presenting a token that was
+// already rotated means it leaked, so every session of that user is revoked.
+// Tokens issued before sessions existed get a session from the given info.
+func (s *SQLStore) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time, session models.SessionInfo) (int, string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	var tokenID, userID int
+	var sessionID sql.NullString
+	var revoked, replaced, expired, userAlive, sessionRevoked bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT rt.token_id, rt.user_id, rt.session_id, rt.revoked_at IS NOT NULL, rt.replaced_by IS NOT NULL,
+			rt.expires_at <= ?, u.isalive = 1, COALESCE(s.revoked_at IS NOT NULL, FALSE)
+		FROM refresh_token rt
+		JOIN user u ON rt.user_id = u.user_id
+		LEFT JOIN user_session s ON rt.session_id = s.session_id
+		WHERE rt.token_hash = ?
+		FOR UPDATE
+	`, now, oldHash).Scan(&tokenID, &userID, &sessionID, &revoked, &replaced, &expired, &userAlive, &sessionRevoked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, "", store.ErrInvalidRefreshToken
+		}
+		return 0, "", err
+	}
+
+	if revoked && replaced {
+		tx.Rollback()
+		if err := s.RevokeAllUserTokens(ctx, userID); err != nil {
+			return 0, "", err
+		}
+		return 0, "", store.ErrRefreshTokenReused
+	}
+	if revoked || expired || !userAlive || sessionRevoked {
+		return 0, "", store.ErrInvalidRefreshToken
+	}
+
+	if sessionID.Valid {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE user_session
+			SET last_seen_at = ?, expires_at = ?
+			WHERE session_id = ?
+		`, now, expiresAt, sessionID.String)
+	} else {
+		sessionID = sql.NullString{String: session.ID, Valid: true}
+		err = createSession(ctx, tx, userID, session, expiresAt)
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	newID, err := tx.InsertContext(ctx, "token_id", `
+		INSERT INTO refresh_token (user_id, token_hash, expires_at, session_id)
+		VALUES (?, ?, ?, ?)
+	`, userID, newHash, expiresAt, sessionID.String)
+	if err != nil {
+		return 0, "", err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE refresh_token
+		SET revoked_at = ?, replaced_by = ?
+		WHERE token_id = ?
+	`, now, newID, tokenID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return userID, sessionID.String, tx.Commit()
+}
+
+// RevokeRefreshToken logs out the session the refresh token belongs to.
+func (s *SQLStore) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	var userID int
+	var sessionID sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id, session_id
+		FROM refresh_token
+		WHERE token_hash = ?
+	`, tokenHash).Scan(&userID, &sessionID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if sessionID.Valid {
+		err := s.RevokeSession(ctx, userID, sessionID.String)
+		if err != nil && !errors.Is(err, store.ErrSessionNotFound) {
+			return err
+		}
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE refresh_token
+		SET revoked_at = ?
+		WHERE token_hash = ?
+		  AND revoked_at IS NULL
+	`, time.Now(), tokenHash)
+	return err
+}
+
+func (s *SQLStore) RevokeAccessToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	now := time.Now()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT IGNORE INTO revoked_token (jti, user_id, expires_at)
+		VALUES (?, ?, ?)
+	`, jti, userID, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, "DELETE FROM revoked_token WHERE expires_at < ?", now)
+	return err
+}
+
+// RevokeAllUserTokens ends every session of a user: bumping token_version
+// invalidates outstanding access tokens and the refresh tokens are revoked so
+// they cannot mint new ones. API keys are left alone, see RevokeUserAPIKeys.
+func (s *SQLStore) RevokeAllUserTokens(ctx context.Context, userID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user
+		SET token_version = token_version + 1
+		WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE refresh_token
+		SET revoked_at = ?
+		WHERE user_id = ?
+		  AND revoked_at IS NULL
+	`, now, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user_session
+		SET revoked_at = ?
+		WHERE user_id = ?
+		  AND revoked_at IS NULL
+	`, now, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IsAccessTokenActive checks an access token against the user's token
+// version, the revoked token list and, when it has one, its session.
+func (s *SQLStore) IsAccessTokenActive(ctx context.Context, userID, tokenVersion int, jti, sessionID string) (bool, error) {
+	var active bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT u.isalive = 1
+			AND u.token_version = ?
+			AND NOT EXISTS (SELECT 1 FROM revoked_token WHERE jti = ?)
+			AND (? = '' OR EXISTS (
+				SELECT 1
+				FROM user_session s
+				WHERE s.session_id = ? AND s.user_id = u.user_id AND s.revoked_at IS NULL
+			))
+		FROM user u
+		WHERE u.user_id = ?
+	`, tokenVersion, jti, sessionID, sessionID, userID).Scan(&active)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return active, err
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"time"
+)
+
+// GetTwoFactorStatus reports whether the user has finished TOTP enrolment and
+// whether any of their roles makes it mandatory.
+func (s *SQLStore) GetTwoFactorStatus(ctx context.Context, userID int) (bool, bool, error) {
+	var enabled, required bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM user_totp WHERE user_id = ? AND enabled_at IS NOT NULL),
+			EXISTS (
+				SELECT 1
+				FROM user_role ur
+				JOIN role r ON ur.role_id = r.role_id
+				WHERE ur.user_id = ? AND r.require_2fa = 1
+			)
+	`, userID, userID).Scan(&enabled, &required)
+	return enabled, required, err
+}
+
+func (s *SQLStore) GetTOTPState(ctx context.Context, userID int) (*models.TOTPState, error) {
+	var state models.TOTPState
+	err := s.db.QueryRowContext(ctx, `
+		SELECT secret, enabled_at IS NOT NULL, last_used_step
+		FROM user_totp
+		WHERE user_id = ?
+	`, userID).Scan(&state.Secret, &state.Enabled, &state.LastUsedStep)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrTwoFactorNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// SavePendingTOTPSecret starts (or restarts) an enrolment. The secret is not
+// used for login until EnableTOTP confirms it.
+func (s *SQLStore) SavePendingTOTPSecret(ctx context.Context, userID int, secret string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES (?, ?)
+		`+s.db.Dialect.upsert("user_id",
+		"secret = CASE WHEN user_totp.enabled_at IS NULL THEN excluded.secret ELSE user_totp.secret END",
+	), userID, secret)
+	return err
+}
+
+// MarkTOTPStepUsed records the time step of an accepted code. It returns false
+// when that step (or a later one) was already used, which blocks replays.
+func (s *SQLStore) MarkTOTPStepUsed(ctx context.Context, userID int, step int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE user_totp
+		SET last_used_step = ?
+		WHERE user_id = ?
+		  AND last_used_step < ?
+	`, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	ra, err := res.RowsAffected()
+	return ra > 0, err
+}
+
+func (s *SQLStore) EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE user_totp SET enabled_at = ? WHERE user_id = ?", time.Now(), userID)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLStore) ReplaceRecoveryCodes(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *Tx, userID int, recoveryCodeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM totp_recovery_code WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO totp_recovery_code (user_id, code_hash)
+			VALUES (?, ?)
+		`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE totp_recovery_code
+		SET used_at = ?
+		WHERE user_id = ?
+		  AND code_hash = ?
+		  AND used_at IS NULL
+	`, time.Now(), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	ra, err := res.RowsAffected()
+	return ra > 0, err
+}
+
+func (s *SQLStore) DisableTOTP(ctx context.Context, userID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM totp_recovery_code WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_totp WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLStore) GetRoleTwoFactorPolicies(ctx context.Context) ([]models.RoleTwoFactorPolicy, error) {
+	policies := []models.RoleTwoFactorPolicy{}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT name, require_2fa FROM role ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.RoleTwoFactorPolicy
+		if err := rows.Scan(&p.Role, &p.Required); err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+
+	return policies, rows.Err()
+}
+
+func (s *SQLStore) SetRoleTwoFactorRequired(ctx context.Context, role string, required bool) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE role SET require_2fa = ? WHERE name = ?", required, role)
+	if err != nil {
+		return err
+	}
+
+	var exists int
+	if ra, _ := res.RowsAffected(); ra == 0 {
+		err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM role WHERE name = ?", role).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			return store.ErrRoleNotFound
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"runtime"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("event_management/backend/database")
+
+// startSpan opens a client span for one statement. It is named after the
+// function in this package that ran it, e.g. ListEvents, so spans line up
+// with the code without naming every query by hand.
+func (d *Dialect) startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	ctx, span := tracer.Start(ctx, "query", trace.WithSpanKind(trace.SpanKindClient))
+	// Looking up the caller is only worth it when the span is exported.
+	if span.IsRecording() {
+		span.SetName(statementName())
+		span.SetAttributes(
+			attribute.String("db.system.name", d.system),
+			attribute.String("db.operation.name", operation(query)),
+			attribute.String("db.query.text", query),
+		)
+	}
+	return ctx, span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Rows is the result of a query. Its span stays open until the rows have been
+// read or closed, so it covers fetching the result as well as running the
+// statement, and records any error met while iterating.
+type Rows struct {
+	*sql.Rows
+	span  trace.Span
+	ended bool
+	// dateColumns marks the DATE columns, once Scan has looked them up.
+	dateColumns []bool
+}
+
+func (r *Rows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.end()
+	return false
+}
+
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.end()
+	return err
+}
+
+func (r *Rows) end() {
+	if !r.ended {
+		r.ended = true
+		endSpan(r.span, r.Rows.Err())
+	}
+}
+
+const packagePrefix = "event_management/backend/database."
+
+// statementName returns the first caller outside the Pool and Tx wrappers.
+func statementName() string {
+	var pcs [10]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		fn := frame.Function
+		if strings.HasPrefix(fn, packagePrefix) && !wrapper(fn) {
+			return spanName(fn)
+		}
+		if !more {
+			return "query"
+		}
+	}
+}
+
+func wrapper(fn string) bool {
+	for _, prefix := range []string{"(*Dialect)", "(*Pool)", "(*Tx)", "insertID"} {
+		if strings.HasPrefix(fn, packagePrefix+prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// spanName turns event_management/backend/database.(*SQLStore).ListEvents.func1
+// into ListEvents.
+func spanName(fn string) string {
+	name := strings.TrimPrefix(fn, packagePrefix)
+	if i := strings.LastIndex(name, ")."); i >= 0 {
+		name = name[i+2:]
+	}
+	if i := strings.Index(name, ".func"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func operation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQuerySpanCoversReadingRows(t *testing.T) {
+	migrateTestDB(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	rows, err := DB.QueryContext(context.Background(), "SELECT name FROM role")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(recorder.Ended()); n != 0 {
+		t.Fatalf("%d span(s) ended before the rows were read", n)
+	}
+
+	for rows.Next() {
+	}
+	if n := len(recorder.Ended()); n != 1 {
+		t.Fatalf("%d span(s) ended after reading the rows, want 1", n)
+	}
+
+	// Closing after the last row must not end the span again.
+	rows.Close()
+	if n := len(recorder.Ended()); n != 1 {
+		t.Fatalf("%d span(s) ended after Close, want 1", n)
+	}
+}
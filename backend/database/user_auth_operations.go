@@ -1,82 +1,100 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"event_management/backend/database/queries"
 	"event_management/backend/models"
+	"event_management/backend/store"
 	"time"
 )
 
-func AuthenticateUser(email string) (*models.User, error) {
+func (s *SQLStore) AuthenticateUser(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
 
-	err := DB.QueryRow(queries.LoginQuery(), email).
-		Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Password, &user.Role)
+	err := s.db.QueryRowContext(ctx, queries.LoginQuery(), email).
+		Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Password, &user.TokenVersion, &user.Verified)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, err
+			return nil, store.ErrUserNotFound
 		}
 		return nil, err
 	}
 
+	if err := loadUserRoles(ctx, s.db, &user); err != nil {
+		return nil, err
+	}
+
 	return &user, nil
 }
 
-func CreateUser(user models.User, hashedPassword []byte) error {
-	tx, err := DB.Begin()
+func (s *SQLStore) CreateUser(ctx context.Context, user models.User, hashedPassword []byte) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-			return
-		}
-		err = tx.Commit()
-	}()
+	defer tx.Rollback()
 
+	userID, err := createUser(ctx, tx, user, hashedPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
+}
+
+func createUser(ctx context.Context, tx *Tx, user models.User, hashedPassword []byte) (int, error) {
 	createdAt := time.Now()
 	isAlive := true
 
+	var verifiedAt interface{}
+	if user.Verified {
+		verifiedAt = createdAt
+	}
+
 	userInsertQuery := `
-		INSERT INTO user (name, email, phone, password, isalive, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO user (name, email, phone, password, isalive, verified_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
-	userResult, err := tx.Exec(userInsertQuery,
-		user.Name, user.Email, user.Phone, hashedPassword, isAlive, createdAt,
+	userID, err := tx.InsertContext(ctx, "user_id", userInsertQuery,
+		user.Name, user.Email, user.Phone, hashedPassword, isAlive, verifiedAt, createdAt,
 	)
 	if err != nil {
-		return err
-	}
-
-	userID, err := userResult.LastInsertId()
-	if err != nil {
-		return err
+		return 0, err
 	}
 
 	var roleID int
-	err = tx.QueryRow("SELECT role_id FROM role WHERE name = ?", user.Role).Scan(&roleID)
+	err = tx.QueryRowContext(ctx, "SELECT role_id FROM role WHERE name = ?", user.Role).Scan(&roleID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	_, err = tx.Exec(`
+	_, err = tx.ExecContext(ctx, `
 		INSERT INTO user_role (user_id, role_id)
 		VALUES (?, ?)
 	`, userID, roleID)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(userID), nil
+}
 
-	return err
+func GetUserRoles(ctx context.Context, userID int) ([]models.Role, error) {
+	return getUserRoles(ctx, DB, userID)
 }
 
-func GetUserRoles(userID int) ([]models.Role, error) {
+func getUserRoles(ctx context.Context, q querier, userID int) ([]models.Role, error) {
 	roles := []models.Role{}
 
-	rows, err := DB.Query(`
-		SELECT r.role_id, r.name, r.description 
+	rows, err := q.QueryContext(ctx, `
+		SELECT r.role_id, r.name, COALESCE(r.description, '')
 		FROM role r
 		JOIN user_role ur ON r.role_id = ur.role_id
 		WHERE ur.user_id = ?
+		ORDER BY r.role_id
 	`, userID)
 	if err != nil {
 		return nil, err
@@ -93,3 +111,19 @@ func GetUserRoles(userID int) ([]models.Role, error) {
 
 	return roles, rows.Err()
 }
+
+// loadUserRoles fills in every role of the user. Role keeps the first one
+// (admin before organiser before attendee) for places that show a single role.
+func loadUserRoles(ctx context.Context, q querier, user *models.User) error {
+	roles, err := getUserRoles(ctx, q, user.ID)
+	if err != nil {
+		return err
+	}
+	if len(roles) == 0 {
+		return errors.New("user has no roles")
+	}
+
+	user.Roles = roles
+	user.Role = roles[0].Name
+	return nil
+}
@@ -1,7 +1,10 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"event_management/backend/models"
+	"event_management/backend/store"
 	"fmt"
 )
 
@@ -10,7 +13,7 @@ type UserData struct {
 	Email string
 }
 
-func getUsersByRole(role string) ([]UserData, error) {
+func getUsersByRole(ctx context.Context, role string) ([]UserData, error) {
 	query := `
 		SELECT u.name, u.email 
 		FROM user u
@@ -19,7 +22,7 @@ func getUsersByRole(role string) ([]UserData, error) {
 		WHERE u.isalive = 1 AND r.name = ?
 	`
 
-	rows, err := DB.Query(query, role)
+	rows, err := DB.QueryContext(ctx, query, role)
 	if err != nil {
 		return nil, err
 	}
@@ -41,47 +44,50 @@ func getUsersByRole(role string) ([]UserData, error) {
 	return users, nil
 }
 
-func GetAllAdmins() ([]UserData, error) {
-	return getUsersByRole("admin")
+func GetAllAdmins(ctx context.Context) ([]UserData, error) {
+	return getUsersByRole(ctx, "admin")
 }
 
-func GetAllOrganisers() ([]UserData, error) {
-	return getUsersByRole("organiser")
+func GetAllOrganisers(ctx context.Context) ([]UserData, error) {
+	return getUsersByRole(ctx, "organiser")
 }
 
-func GetAllAttendees() ([]UserData, error) {
-	return getUsersByRole("attendee")
+func GetAllAttendees(ctx context.Context) ([]UserData, error) {
+	return getUsersByRole(ctx, "attendee")
 }
 
-type UserWithRole struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	Role  string `json:"role"`
-}
-
-func GetAllUserRoles() ([]UserWithRole, error) {
+func (s *SQLStore) ListUsersWithRoles(ctx context.Context) ([]models.UserWithRoles, error) {
 	query := `
-		SELECT u.name, u.email, r.name as role
+		SELECT u.user_id, u.name, u.email, r.name as role
 		FROM user u
 		JOIN user_role ur ON u.user_id = ur.user_id
 		JOIN role r ON ur.role_id = r.role_id
 		WHERE u.isalive = 1
-		ORDER BY u.name
+		ORDER BY u.name, u.user_id, r.role_id
 	`
 
-	rows, err := DB.Query(query)
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var allUsers []UserWithRole
+	var allUsers []models.UserWithRoles
+	lastUserID := 0
 	for rows.Next() {
-		var user UserWithRole
-		if err := rows.Scan(&user.Name, &user.Email, &user.Role); err != nil {
+		var userID int
+		var user models.UserWithRoles
+		if err := rows.Scan(&userID, &user.Name, &user.Email, &user.Role); err != nil {
 			return nil, err
 		}
+		if userID == lastUserID {
+			last := &allUsers[len(allUsers)-1]
+			last.Roles = append(last.Roles, user.Role)
+			continue
+		}
+		user.Roles = []string{user.Role}
 		allUsers = append(allUsers, user)
+		lastUserID = userID
 	}
 
 	if err = rows.Err(); err != nil {
@@ -91,63 +97,137 @@ func GetAllUserRoles() ([]UserWithRole, error) {
 	return allUsers, nil
 }
 
-func DeactivateUser(email string, role string) error {
+func (s *SQLStore) activeUserID(ctx context.Context, email string) (int, error) {
 	var userID int
-	err := DB.QueryRow("SELECT user_id FROM user WHERE email = ? AND isalive = 1", email).Scan(&userID)
-	if err != nil {
-		return fmt.Errorf("no user found with email %s", email)
+	err := s.db.QueryRowContext(ctx, "SELECT user_id FROM user WHERE email = ? AND isalive = 1", email).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: %s", store.ErrUserNotFound, email)
 	}
+	return userID, err
+}
 
+func (s *SQLStore) roleID(ctx context.Context, role string) (int, error) {
 	var roleID int
-	err = DB.QueryRow("SELECT role_id FROM role WHERE name = ?", role).Scan(&roleID)
+	err := s.db.QueryRowContext(ctx, "SELECT role_id FROM role WHERE name = ?", role).Scan(&roleID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: %s", store.ErrInvalidRole, role)
+	}
+	return roleID, err
+}
+
+func (s *SQLStore) DeactivateUser(ctx context.Context, email string, role string) error {
+	userID, err := s.activeUserID(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	roleID, err := s.roleID(ctx, role)
 	if err != nil {
-		return fmt.Errorf("invalid role: %s", role)
+		return err
 	}
 
 	var count int
-	err = DB.QueryRow(
+	err = s.db.QueryRowContext(ctx,
 		"SELECT COUNT(*) FROM user_role WHERE user_id = ? AND role_id = ?",
 		userID, roleID,
 	).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: %s is not %s", store.ErrRoleNotAssigned, email, role)
+	}
+
+	_, err = s.db.ExecContext(ctx, "UPDATE user SET isalive = 0 WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.RevokeAllUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	return s.RevokeUserAPIKeys(ctx, userID)
+}
+
+func (s *SQLStore) GrantRole(ctx context.Context, email, role string) error {
+	userID, err := s.activeUserID(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	roleID, err := s.roleID(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT IGNORE INTO user_role (user_id, role_id)
+		VALUES (?, ?)
+	`, userID, roleID)
+	return err
+}
+
+// RevokeRole removes one role from a user. The user's sessions are ended so
+// that tokens carrying the old role set stop working.
+func (s *SQLStore) RevokeRole(ctx context.Context, email, role string) error {
+	userID, err := s.activeUserID(ctx, email)
+	if err != nil {
+		return err
+	}
 
-	if err != nil || count == 0 {
-		return fmt.Errorf("no user found with email %s and role %s", email, role)
+	var hasRole bool
+	var count int
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN r.name = ? THEN 1 ELSE 0 END), 0) > 0, COUNT(*)
+		FROM user_role ur
+		JOIN role r ON ur.role_id = r.role_id
+		WHERE ur.user_id = ?
+	`, role, userID).Scan(&hasRole, &count)
+	if err != nil {
+		return err
+	}
+	if !hasRole {
+		return fmt.Errorf("%w: %s is not %s", store.ErrRoleNotAssigned, email, role)
+	}
+	if count <= 1 {
+		return store.ErrLastRole
 	}
 
-	_, err = DB.Exec("UPDATE user SET isalive = 0 WHERE user_id = ?", userID)
+	_, err = s.db.ExecContext(ctx, `
+		DELETE FROM user_role
+		WHERE user_id = ?
+		  AND role_id = (SELECT role_id FROM role WHERE name = ?)
+	`, userID, role)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return s.RevokeAllUserTokens(ctx, userID)
 }
 
-func GetUserByID(userID int) (models.User, error) {
+func (s *SQLStore) GetUser(ctx context.Context, userID int) (models.User, error) {
 	var user models.User
 
-	err := DB.QueryRow(`
-		SELECT user_id, name, email, phone, password
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id, name, email, phone, password, token_version, verified_at IS NOT NULL
 		FROM user
 		WHERE user_id = ? AND isalive = 1
-	`, userID).Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Password)
+	`, userID).Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Password, &user.TokenVersion, &user.Verified)
 
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return user, store.ErrUserNotFound
+		}
 		return user, err
 	}
 
-	err = DB.QueryRow(`
-		SELECT r.name
-		FROM role r
-		JOIN user_role ur ON r.role_id = ur.role_id
-		WHERE ur.user_id = ?
-		LIMIT 1
-	`, userID).Scan(&user.Role)
+	err = loadUserRoles(ctx, s.db, &user)
 
 	return user, err
 }
 
-func UpdateUser(user models.User) (models.User, error) {
-	_, err := DB.Exec(`
+func (s *SQLStore) UpdateProfile(ctx context.Context, user models.User) (models.User, error) {
+	_, err := s.db.ExecContext(ctx, `
 		UPDATE user
 		SET name = ?, phone = ?
 		WHERE user_id = ? AND isalive = 1
@@ -157,13 +237,13 @@ func UpdateUser(user models.User) (models.User, error) {
 		return user, err
 	}
 
-	return GetUserByID(user.ID)
+	return s.GetUser(ctx, user.ID)
 }
 
-func GetRegistrationsByUserID(userID int) ([]models.Registration, error) {
+func (s *SQLStore) ListRegistrationsByUser(ctx context.Context, userID int) ([]models.Registration, error) {
 	var registrations []models.Registration
 
-	rows, err := DB.Query(`
+	rows, err := s.db.QueryContext(ctx, `
 		SELECT registration_id, event_id, attendee_id, registration_date, status
 		FROM registration
 		WHERE attendee_id = ? AND isalive = 1
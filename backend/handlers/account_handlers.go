@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (s *Server) ExportAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := s.Users.ExportAccount(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			writeJSONError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error exporting account data", "error", err)
+		writeJSONError(w, "Failed to export account data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="eventease-account-%d.json"`, userID))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(export)
+}
+
+// DeleteAccountHandler anonymises the caller's account after confirming
+// their password.
+func (s *Server) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		writeJSONError(w, "Password is required to delete the account", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.Users.GetUser(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		writeJSONError(w, "Password is incorrect", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.Users.DeleteAccount(r.Context(), userID); err != nil {
+		if errors.Is(err, store.ErrUpcomingEvents) {
+			writeJSONError(w, "Cancel your upcoming events before deleting your account", http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error deleting account", "error", err)
+		writeJSONError(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Account deleted"})
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"event_management/backend/logging"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	apiKeyPrefix        = "em_"
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+	maxAPIKeyLifetime   = 365
+)
+
+type contextKey string
+
+// apiKeyOwnerKey holds the *models.APIKeyOwner of a request made with an API
+// key.
+const apiKeyOwnerKey contextKey = "apiKeyOwner"
+
+// serveWithAPIKey authenticates a request carrying an API key and passes it
+// on with only the key's owner in the context. The caller is not signed in
+// until a permissionGate has checked the key's scopes, see withAPIKeyOwner,
+// so a route without a declared permission does not see a user at all.
+func (s *Server) serveWithAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, key string) {
+	owner, err := s.APIKeys.AuthenticateAPIKey(r.Context(), utils.HashToken(key))
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidAPIKey) {
+			writeJSONError(w, "Unauthorized. Invalid or expired API key.", http.StatusUnauthorized)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error verifying API key", "error", err)
+		writeJSONError(w, "Error verifying token", http.StatusInternalServerError)
+		return
+	}
+
+	next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyOwnerKey, owner)))
+}
+
+// withAPIKeyOwner sets the context values a JWT would set for the owner of
+// an API key, plus the key's scopes.
+func withAPIKeyOwner(ctx context.Context, owner *models.APIKeyOwner) context.Context {
+	logging.SetUserID(ctx, owner.User.ID)
+	ctx = context.WithValue(ctx, utils.UserIDKey, owner.User.ID)
+	ctx = context.WithValue(ctx, utils.UserEmailKey, owner.User.Email)
+	ctx = context.WithValue(ctx, utils.UserNameKey, owner.User.Name)
+	ctx = context.WithValue(ctx, utils.UserRoleKey, owner.User.Role)
+	ctx = context.WithValue(ctx, utils.UserRolesKey, owner.User.RoleNames())
+	return context.WithValue(ctx, utils.APIKeyScopesKey, owner.Scopes)
+}
+
+// RequireSession rejects API keys and impersonation tokens, for account
+// settings that only the user themselves should change.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(apiKeyOwnerKey).(*models.APIKeyOwner); ok {
+			writeJSONError(w, "This endpoint cannot be used with an API key", http.StatusForbidden)
+			return
+		}
+		if _, ok := r.Context().Value(utils.ImpersonatorIDKey).(int); ok {
+			writeJSONError(w, "This action is not allowed while impersonating a user", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expiresInDays"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		writeJSONError(w, "name is required and must be at most 100 characters", http.StatusBadRequest)
+		return
+	}
+	if len(req.Scopes) == 0 {
+		writeJSONError(w, "At least one scope is required", http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAPIKeyLifetime {
+		writeJSONError(w, "expiresInDays must be between 0 (no expiry) and 365", http.StatusBadRequest)
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		allowed, err := s.hasPermission(r, scope)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Error checking permission", "permission", scope, "error", err)
+			writeJSONError(w, "Error checking permissions", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			writeJSONError(w, "You cannot grant a scope you do not have: "+scope, http.StatusBadRequest)
+			return
+		}
+	}
+
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		writeJSONError(w, "Failed to generate API key", http.StatusInternalServerError)
+		return
+	}
+	key := apiKeyPrefix + secret
+	prefix := key[:apiKeyDisplayLength]
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	id, err := s.APIKeys.CreateAPIKey(r.Context(), userID, name, prefix, utils.HashToken(key), req.Scopes, expiresAt)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error creating API key", "error", err)
+		writeJSONError(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"id":     id,
+		"name":   name,
+		"prefix": prefix,
+		"scopes": req.Scopes,
+		"key":    key,
+	}
+	if expiresAt != nil {
+		resp["expiresAt"] = expiresAt.UTC().Format(time.RFC3339)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := s.APIKeys.GetAPIKeys(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error retrieving API keys", "error", err)
+		writeJSONError(w, "Failed to retrieve API keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+func (s *Server) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keyID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.APIKeys.RevokeAPIKey(r.Context(), userID, keyID); err != nil {
+		if errors.Is(err, store.ErrAPIKeyNotFound) {
+			writeJSONError(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error revoking API key", "error", err)
+		writeJSONError(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "API key revoked"})
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"event_management/backend/utils"
+)
+
+func TestAPIKeysOnlyReachRoutesWithAPermission(t *testing.T) {
+	srv, mem := newTestServer(t)
+	userID := addTestUser(t, mem, "alice@example.com")
+
+	const key = apiKeyPrefix + "test-key"
+	if _, err := mem.CreateAPIKey(t.Context(), userID, "ci", key[:apiKeyDisplayLength], utils.HashToken(key), []string{"event:list"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// whoami answers with the signed-in user, or 401 when there is none.
+	whoami := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := r.Context().Value(utils.UserIDKey).(int); !ok || id != userID {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		want    int
+	}{
+		{"scoped permission", srv.RequirePermission("event:list")(whoami), http.StatusOK},
+		{"permission outside the scopes", srv.RequirePermission("profile:view")(whoami), http.StatusForbidden},
+		{"no permission declared", whoami, http.StatusUnauthorized},
+		{"session-only route", RequireSession(whoami), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/events", nil)
+			req.Header.Set("Authorization", "Bearer "+key)
+			rec := httptest.NewRecorder()
+			srv.JWTMiddleware(tt.handler).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"event_management/backend/utils"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	sessionCookieName = "session"
+	refreshCookieName = "refresh_token"
+	csrfCookieName    = "csrf_token"
+	csrfHeaderName    = "X-CSRF-Token"
+
+	oidcStateCookieName = "oidc_state"
+	oidcLinkCookieName  = "oidc_link"
+	identitiesPath      = "/user/identities"
+)
+
+// refreshCookiePaths are the only routes that read the refresh cookie. A
+// cookie has a single path, so it is set once for each of them.
+var refreshCookiePaths = []string{"/token/refresh", "/logout"}
+
+var (
+	errNoCredentials    = errors.New("no credentials")
+	errBadAuthorization = errors.New("malformed authorization header")
+)
+
+type CookieConfig struct {
+	// Enabled switches token delivery from the response body to cookies.
+	// Bearer headers keep working either way.
+	Enabled  bool
+	Secure   bool
+	SameSite http.SameSite
+	Domain   string
+}
+
+var cookieConfig = CookieConfig{Secure: true, SameSite: http.SameSiteLaxMode}
+
+func ConfigureCookies(cfg CookieConfig) error {
+	if cfg.SameSite == http.SameSiteNoneMode && !cfg.Secure {
+		return errors.New("SameSite=None cookies must be Secure")
+	}
+	cookieConfig = cfg
+	return nil
+}
+
+// ParseSameSite reads lax, strict or none. An empty string means lax.
+func ParseSameSite(s string) (http.SameSite, error) {
+	switch strings.ToLower(s) {
+	case "", "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("invalid SameSite value %q", s)
+}
+
+// deliverTokens adds the tokens to a response body or, in cookie mode, sets
+// them as HttpOnly cookies together with a fresh CSRF token.
+func deliverTokens(w http.ResponseWriter, resp map[string]interface{}, token, refreshToken string) error {
+	if !cookieConfig.Enabled {
+		resp["token"] = token
+		resp["refresh_token"] = refreshToken
+		return nil
+	}
+
+	csrfToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	setCookie(w, sessionCookieName, token, "/", utils.AccessTokenTTL, true)
+	for _, path := range refreshCookiePaths {
+		setCookie(w, refreshCookieName, refreshToken, path, utils.RefreshTokenTTL, true)
+	}
+	setCookie(w, csrfCookieName, csrfToken, "/", utils.RefreshTokenTTL, false)
+
+	resp["csrf_token"] = csrfToken
+	return nil
+}
+
+func clearAuthCookies(w http.ResponseWriter) {
+	setCookie(w, sessionCookieName, "", "/", -1, true)
+	for _, path := range refreshCookiePaths {
+		setCookie(w, refreshCookieName, "", path, -1, true)
+	}
+	setCookie(w, csrfCookieName, "", "/", -1, false)
+}
+
+func setCookie(w http.ResponseWriter, name, value, path string, ttl time.Duration, httpOnly bool) {
+	maxAge := int(ttl.Seconds())
+	if ttl < 0 {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   cookieConfig.Domain,
+		MaxAge:   maxAge,
+		Secure:   cookieConfig.Secure,
+		HttpOnly: httpOnly,
+		SameSite: cookieConfig.SameSite,
+	})
+}
+
+// setOIDCStateCookie ties an OIDC login to the browser that started it. It is
+// always Lax, as the callback is a cross-site navigation from the identity
+// provider that a Strict cookie would not survive, and only sent to the OIDC
+// routes.
+func setOIDCStateCookie(w http.ResponseWriter, state string, ttl time.Duration) {
+	maxAge := int(ttl.Seconds())
+	if ttl < 0 {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state,
+		Path:     "/auth/oidc",
+		MaxAge:   maxAge,
+		Secure:   cookieConfig.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// setOIDCLinkCookie hands the identity link token of a failed single sign-on
+// to the browser that did the sign-on. Only that browser can then link the
+// identity, and only through LinkIdentityHandler, the one route it is sent to.
+func setOIDCLinkCookie(w http.ResponseWriter, token string, ttl time.Duration) {
+	maxAge := int(ttl.Seconds())
+	if ttl < 0 {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcLinkCookieName,
+		Value:    token,
+		Path:     identitiesPath,
+		MaxAge:   maxAge,
+		Secure:   cookieConfig.Secure,
+		HttpOnly: true,
+		// The frontend posts the link request the way it sends the session
+		// cookie, so the link cookie has to travel the same way.
+		SameSite: cookieConfig.SameSite,
+	})
+}
+
+// accessToken returns the Bearer token of a request or, in cookie mode, the
+// session cookie. fromCookie tells the caller that CSRF checks apply.
+func accessToken(r *http.Request) (token string, fromCookie bool, err error) {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			return "", false, errBadAuthorization
+		}
+		return parts[1], false, nil
+	}
+
+	if cookieConfig.Enabled {
+		if c, err := r.Cookie(sessionCookieName); err == nil && c.Value != "" {
+			return c.Value, true, nil
+		}
+	}
+
+	return "", false, errNoCredentials
+}
+
+// refreshTokenFromRequest prefers the form value and falls back to the
+// refresh cookie in cookie mode.
+func refreshTokenFromRequest(r *http.Request) (token string, fromCookie bool) {
+	if token := r.FormValue("refresh_token"); token != "" {
+		return token, false
+	}
+	if cookieConfig.Enabled {
+		if c, err := r.Cookie(refreshCookieName); err == nil && c.Value != "" {
+			return c.Value, true
+		}
+	}
+	return "", false
+}
+
+// validCSRF implements the double-submit check: state-changing requests
+// authenticated by cookie must echo the CSRF cookie in a header, which a
+// cross-site page cannot read.
+func validCSRF(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	c, err := r.Cookie(csrfCookieName)
+	if err != nil || c.Value == "" {
+		return false
+	}
+	header := r.Header.Get(csrfHeaderName)
+	return subtle.ConstantTimeCompare([]byte(c.Value), []byte(header)) == 1
+}
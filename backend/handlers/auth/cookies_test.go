@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestRefreshCookieOnlyGoesToRefreshAndLogout(t *testing.T) {
+	prev := cookieConfig
+	if err := ConfigureCookies(CookieConfig{Enabled: true, Secure: true, SameSite: http.SameSiteLaxMode}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cookieConfig = prev })
+
+	srv, mem := newTestServer(t)
+	addTestUser(t, mem, "alice@example.com")
+
+	rec := postForm(srv.LoginHandler, "/login", url.Values{"email": {"alice@example.com"}, "password": {testPassword}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login status = %d, want 200 (%s)", rec.Code, rec.Body)
+	}
+
+	var paths []string
+	for _, c := range rec.Result().Cookies() {
+		switch c.Name {
+		case refreshCookieName:
+			paths = append(paths, c.Path)
+		case sessionCookieName, csrfCookieName:
+			if c.Path != "/" {
+				t.Errorf("%s cookie path = %q, want /", c.Name, c.Path)
+			}
+		}
+	}
+	slices.Sort(paths)
+	if want := []string{"/logout", "/token/refresh"}; !slices.Equal(paths, want) {
+		t.Errorf("refresh cookie paths = %q, want %q", paths, want)
+	}
+}
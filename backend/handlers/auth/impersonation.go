@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"event_management/backend/logging"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ImpersonationReadOnly blocks every state-changing request made with an
+// impersonation token. Account settings are always blocked by RequireSession.
+var ImpersonationReadOnly = true
+
+// serveImpersonated runs a request made by an admin acting as another user.
+// Every such request is written to the log and the audit table.
+func (s *Server) serveImpersonated(w http.ResponseWriter, r *http.Request, next http.Handler, claims *utils.Claims) {
+	active, err := s.Impersonations.IsImpersonationActive(r.Context(), claims.ID, claims.ImpersonatorID)
+	if err != nil {
+		writeJSONError(w, "Error verifying token", http.StatusInternalServerError)
+		return
+	}
+	if !active {
+		writeJSONError(w, "Unauthorized. Impersonation has ended.", http.StatusUnauthorized)
+		return
+	}
+
+	logging.SetImpersonatorID(r.Context(), claims.ImpersonatorID)
+	rec := logging.NewStatusRecorder(w)
+	defer func() {
+		slog.InfoContext(r.Context(), "Impersonated request",
+			"method", r.Method, "path", r.URL.Path, "status", rec.Status())
+		if err := s.Impersonations.LogImpersonationRequest(r.Context(), claims.ID, r.Method, r.URL.Path, rec.Status()); err != nil {
+			slog.ErrorContext(r.Context(), "Error recording impersonated request", "error", err)
+		}
+	}()
+
+	if ImpersonationReadOnly && !isSafeMethod(r.Method) {
+		writeJSONError(rec, "This action is not allowed while impersonating a user", http.StatusForbidden)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), utils.ImpersonatorIDKey, claims.ImpersonatorID)
+	next.ServeHTTP(rec, r.WithContext(ctx))
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func (s *Server) StartImpersonationHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if _, impersonating := r.Context().Value(utils.ImpersonatorIDKey).(int); impersonating {
+		writeJSONError(w, "Cannot start an impersonation while impersonating", http.StatusForbidden)
+		return
+	}
+
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if userID == adminID {
+		writeJSONError(w, "You cannot impersonate yourself", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		writeJSONError(w, "A reason is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.Users.GetUser(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if containsString(user.RoleNames(), "admin") {
+		writeJSONError(w, "Admins cannot be impersonated", http.StatusForbidden)
+		return
+	}
+
+	token, claims, err := utils.GenerateImpersonationJWT(user.ID, user.Email, user.Name, user.RoleNames(), user.TokenVersion, adminID)
+	if err != nil {
+		writeJSONError(w, "Failed to generate impersonation token", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := s.Impersonations.CreateImpersonation(r.Context(), adminID, user.ID, strings.TrimSpace(req.Reason), claims.ID, clientIP(r), claims.ExpiresAt.Time)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error recording impersonation", "error", err)
+		writeJSONError(w, "Failed to start impersonation", http.StatusInternalServerError)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Impersonation started",
+		"admin_id", adminID, "target_user_id", user.ID, "reason", req.Reason)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         id,
+		"token":      token,
+		"expires_in": int(utils.ImpersonationTTL.Seconds()),
+		"expiresAt":  claims.ExpiresAt.Time.UTC().Format(time.RFC3339),
+		"user": map[string]interface{}{
+			"id":    user.ID,
+			"name":  user.Name,
+			"email": user.Email,
+			"roles": user.RoleNames(),
+		},
+	})
+}
+
+// EndImpersonationHandler is called with the impersonation token itself. It
+// sits outside JWTMiddleware so it also works in read-only mode.
+func (s *Server) EndImpersonationHandler(w http.ResponseWriter, r *http.Request) {
+	tokenString, _, err := accessToken(r)
+	if err != nil {
+		writeJSONError(w, "Authorization header required", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := utils.ValidateJWT(tokenString)
+	if err != nil || claims.ImpersonatorID == 0 {
+		writeJSONError(w, "Not an impersonation token", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Impersonations.EndImpersonation(r.Context(), claims.ID); err != nil {
+		if errors.Is(err, store.ErrImpersonationNotFound) {
+			writeJSONError(w, "Impersonation already ended", http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error ending impersonation", "error", err)
+		writeJSONError(w, "Failed to end impersonation", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.Sessions.RevokeAccessToken(r.Context(), claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+		slog.ErrorContext(r.Context(), "Error revoking impersonation token", "error", err)
+	}
+
+	slog.InfoContext(r.Context(), "Impersonation ended",
+		"admin_id", claims.ImpersonatorID, "target_user_id", claims.UserID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Impersonation ended"})
+}
+
+func (s *Server) GetImpersonationsHandler(w http.ResponseWriter, r *http.Request) {
+	impersonations, err := s.Impersonations.GetImpersonations(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error retrieving impersonations", "error", err)
+		writeJSONError(w, "Failed to retrieve impersonations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(impersonations)
+}
+
+func (s *Server) GetImpersonationRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, "Invalid impersonation ID", http.StatusBadRequest)
+		return
+	}
+
+	requests, err := s.Impersonations.GetImpersonationRequests(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrImpersonationNotFound) {
+			writeJSONError(w, "Impersonation not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error retrieving impersonation requests", "error", err)
+		writeJSONError(w, "Failed to retrieve impersonation requests", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requests)
+}
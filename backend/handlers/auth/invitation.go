@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"event_management/backend/mail"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultInvitationTTL = 72 * time.Hour
+	maxInvitationTTL     = 30 * 24 * time.Hour
+	maxInvitationUses    = 100
+)
+
+var invitableRoles = map[string]bool{
+	"admin":     true,
+	"organiser": true,
+}
+
+func (s *Server) CreateInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Role           string `json:"role"`
+		Email          string `json:"email"`
+		ExpiresInHours int    `json:"expiresInHours"`
+		MaxUses        int    `json:"maxUses"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	role := strings.ToLower(req.Role)
+	if !invitableRoles[role] {
+		writeJSONError(w, "Invitations can only be issued for the admin or organiser role", http.StatusBadRequest)
+		return
+	}
+
+	ttl := defaultInvitationTTL
+	if req.ExpiresInHours != 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+	if ttl <= 0 || ttl > maxInvitationTTL {
+		writeJSONError(w, "expiresInHours must be between 1 and 720", http.StatusBadRequest)
+		return
+	}
+
+	maxUses := req.MaxUses
+	if maxUses == 0 || req.Email != "" {
+		maxUses = 1
+	}
+	if maxUses < 1 || maxUses > maxInvitationUses {
+		writeJSONError(w, "maxUses must be between 1 and 100", http.StatusBadRequest)
+		return
+	}
+
+	expiresAt := time.Now().Add(ttl)
+
+	id, err := s.Invitations.CreateInvitation(r.Context(), role, req.Email, maxUses, expiresAt, adminID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error creating invitation", "error", err)
+		writeJSONError(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := utils.GenerateInvitationToken(id, role, strings.ToLower(req.Email), expiresAt)
+	if err != nil {
+		writeJSONError(w, "Failed to sign invitation", http.StatusInternalServerError)
+		return
+	}
+	link := frontendLink("/signup/invitation", token)
+
+	if req.Email != "" {
+		err := mail.Send(mail.Message{
+			To:      req.Email,
+			Subject: "You have been invited to EventEase",
+			Body: fmt.Sprintf(
+				"You have been invited to join EventEase as %s.\n\nCreate your account with the link below before %s.\n\n%s\n",
+				role, expiresAt.UTC().Format(time.RFC1123), link,
+			),
+		})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Error sending invitation email", "error", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":        id,
+		"role":      role,
+		"email":     req.Email,
+		"maxUses":   maxUses,
+		"expiresAt": expiresAt.UTC().Format(time.RFC3339),
+		"token":     token,
+		"link":      link,
+	})
+}
+
+func (s *Server) GetInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	invitations, err := s.Invitations.GetInvitations(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error retrieving invitations", "error", err)
+		writeJSONError(w, "Failed to retrieve invitations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+func (s *Server) RevokeInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Invitations.RevokeInvitation(r.Context(), id); err != nil {
+		if errors.Is(err, store.ErrInvitationNotFound) {
+			writeJSONError(w, "Invitation not found or already revoked", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error revoking invitation", "error", err)
+		writeJSONError(w, "Failed to revoke invitation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Invitation revoked"})
+}
+
+func (s *Server) GetInvitationRedemptionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	redemptions, err := s.Invitations.GetInvitationRedemptions(r.Context(), id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error retrieving invitation redemptions", "error", err)
+		writeJSONError(w, "Failed to retrieve redemptions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(redemptions)
+}
+
+func (s *Server) InvitationSignupHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	token := r.FormValue("token")
+	name := r.FormValue("name")
+	email := r.FormValue("email")
+	password := r.FormValue("password")
+	phone := r.FormValue("phone")
+
+	if token == "" || name == "" || email == "" || password == "" || phone == "" {
+		writeJSONError(w, "All fields are required", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := utils.ValidateInvitationToken(token)
+	if err != nil {
+		writeJSONError(w, "Invalid or expired invitation", http.StatusBadRequest)
+		return
+	}
+
+	if err := utils.CheckPasswordPolicy(password, email); err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		writeJSONError(w, "Error hashing password", http.StatusInternalServerError)
+		return
+	}
+
+	user := models.User{
+		Name:  name,
+		Email: email,
+		Phone: phone,
+	}
+
+	userID, err := s.Invitations.RedeemInvitation(r.Context(), claims.InvitationID, user, hashedPassword, clientIP(r), r.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidInvitation):
+			writeJSONError(w, "Invalid or expired invitation", http.StatusBadRequest)
+		case errors.Is(err, store.ErrInvitationEmailMismatch):
+			writeJSONError(w, "This invitation was issued for a different email address", http.StatusForbidden)
+		default:
+			writeJSONError(w, "Email already registered or DB error", http.StatusBadRequest)
+		}
+		return
+	}
+
+	message := "Signup successful!"
+	if claims.Email == "" {
+		if err := s.sendVerificationEmail(r.Context(), userID, email); err != nil {
+			slog.ErrorContext(r.Context(), "Error sending verification email", "error", err)
+		}
+		message = "Signup successful! Please check your email to verify your address."
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.AuthResponse{
+		Message: message,
+		Name:    name,
+		Email:   email,
+		Role:    claims.Role,
+	})
+}
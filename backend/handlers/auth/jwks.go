@@ -0,0 +1,15 @@
+package auth
+
+import (
+	"encoding/json"
+	"event_management/backend/utils"
+	"net/http"
+)
+
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": utils.PublicJWKs(),
+	})
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"event_management/backend/metrics"
+	"event_management/backend/utils"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	freeLoginAttempts = 5
+	baseLockout       = 30 * time.Second
+	maxLockout        = time.Hour
+	lockoutResetAfter = 24 * time.Hour
+)
+
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+// compareDummyPassword is used when the email is unknown so that the response
+// takes as long as for a real account. The hash is made on first use, after
+// the bcrypt cost has been configured.
+func compareDummyPassword(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = utils.HashPassword("dummy-password-for-timing")
+	})
+	bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func lockoutKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func lockoutDuration(attempts int) time.Duration {
+	d := baseLockout
+	for i := freeLoginAttempts; i < attempts && d < maxLockout; i++ {
+		d *= 2
+	}
+	if d > maxLockout {
+		d = maxLockout
+	}
+	return d
+}
+
+func writeTooManyRequests(w http.ResponseWriter, message string, retryAfter time.Duration) {
+	seconds := int(retryAfter.Round(time.Second).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeJSONError(w, message, http.StatusTooManyRequests)
+}
+
+func (s *Server) allowLoginAttempt(w http.ResponseWriter, r *http.Request) bool {
+	if ok, wait := s.loginLimiter.Allow(clientIP(r)); !ok {
+		writeTooManyRequests(w, "Too many login attempts. Please try again later.", wait)
+		return false
+	}
+	return true
+}
+
+// recordFailedLogin counts a failure at either login step against the
+// account, so wrong two-factor codes lock it out just like wrong passwords.
+func (s *Server) recordFailedLogin(ctx context.Context, email, step string) {
+	metrics.FailedLogins.WithLabelValues(step).Inc()
+	key := lockoutKey(email)
+
+	attempts, err := s.Lockouts.RecordFailedLogin(ctx, key, lockoutResetAfter)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error recording failed login", "error", err)
+		return
+	}
+
+	if attempts >= freeLoginAttempts {
+		if err := s.Lockouts.LockAccount(ctx, key, time.Now().Add(lockoutDuration(attempts))); err != nil {
+			slog.ErrorContext(ctx, "Error locking account", "error", err)
+		}
+	}
+}
+
+func (s *Server) GetLockoutsHandler(w http.ResponseWriter, r *http.Request) {
+	lockouts, err := s.Lockouts.GetLoginLockouts(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error retrieving lockouts", "error", err)
+		writeJSONError(w, "Failed to retrieve lockouts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lockouts)
+}
+
+func (s *Server) ClearLockoutHandler(w http.ResponseWriter, r *http.Request) {
+	email := lockoutKey(mux.Vars(r)["email"])
+
+	cleared, err := s.Lockouts.ClearLockout(r.Context(), email)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error clearing lockout", "error", err)
+		writeJSONError(w, "Failed to clear lockout", http.StatusInternalServerError)
+		return
+	}
+	if !cleared {
+		writeJSONError(w, "No lockout found for this email", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Lockout cleared",
+		"email":   email,
+	})
+}
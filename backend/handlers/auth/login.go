@@ -3,8 +3,12 @@ package auth
 import (
 	"context"
 	"encoding/json"
-	"event_management/backend/database"
+	"errors"
+	"event_management/backend/logging"
+	"event_management/backend/models"
+	"event_management/backend/store"
 	"event_management/backend/utils"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -19,36 +23,77 @@ func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
 	})
 }
 
-func JWTMiddleware(next http.Handler) http.Handler {
+func (s *Server) JWTMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
+		tokenString, fromCookie, err := accessToken(r)
+		if err != nil {
+			if errors.Is(err, errBadAuthorization) {
+				writeJSONError(w, "Invalid authorization format", http.StatusUnauthorized)
+				return
+			}
 			writeJSONError(w, "Authorization header required", http.StatusUnauthorized)
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			writeJSONError(w, "Invalid authorization format", http.StatusUnauthorized)
+		if fromCookie && !validCSRF(r) {
+			writeJSONError(w, "Missing or invalid CSRF token", http.StatusForbidden)
+			return
+		}
+
+		if !fromCookie && strings.HasPrefix(tokenString, apiKeyPrefix) {
+			s.serveWithAPIKey(w, r, next, tokenString)
 			return
 		}
 
-		claims, err := utils.ValidateJWT(parts[1])
+		claims, err := utils.ValidateJWT(tokenString)
 		if err != nil {
 			writeJSONError(w, "Unauthorized. Invalid or expired token.", http.StatusUnauthorized)
 			return
 		}
 
+		active, err := s.Sessions.IsAccessTokenActive(r.Context(), claims.UserID, claims.TokenVersion, claims.ID, claims.SessionID)
+		if err != nil {
+			writeJSONError(w, "Error verifying token", http.StatusInternalServerError)
+			return
+		}
+		if !active {
+			writeJSONError(w, "Unauthorized. Token has been revoked.", http.StatusUnauthorized)
+			return
+		}
+
+		if claims.SessionID != "" {
+			if err := s.Sessions.TouchSession(r.Context(), claims.SessionID); err != nil {
+				slog.ErrorContext(r.Context(), "Error updating session last-seen time", "error", err)
+			}
+		}
+
+		roles := claims.Roles
+		if len(roles) == 0 && claims.Role != "" {
+			roles = []string{claims.Role}
+		}
+
+		logging.SetUserID(r.Context(), claims.UserID)
 		ctx := context.WithValue(r.Context(), utils.UserIDKey, claims.UserID)
 		ctx = context.WithValue(ctx, utils.UserEmailKey, claims.Email)
 		ctx = context.WithValue(ctx, utils.UserNameKey, claims.Name)
 		ctx = context.WithValue(ctx, utils.UserRoleKey, claims.Role)
+		ctx = context.WithValue(ctx, utils.UserRolesKey, roles)
+		ctx = context.WithValue(ctx, utils.SessionIDKey, claims.SessionID)
+
+		if claims.ImpersonatorID != 0 {
+			s.serveImpersonated(w, r.WithContext(ctx), next, claims)
+			return
+		}
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.allowLoginAttempt(w, r) {
+		return
+	}
+
 	if err := r.ParseForm(); err != nil {
 		writeJSONError(w, "Invalid form data", http.StatusBadRequest)
 		return
@@ -62,47 +107,112 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := database.AuthenticateUser(email)
+	remaining, err := s.Lockouts.GetLockoutRemaining(r.Context(), lockoutKey(email))
 	if err != nil {
+		slog.ErrorContext(r.Context(), "Error checking account lockout", "error", err)
+		writeJSONError(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+	if remaining > 0 {
+		writeTooManyRequests(w, "Too many failed login attempts. Please try again later.", remaining)
+		return
+	}
+
+	user, err := s.Credentials.AuthenticateUser(r.Context(), email)
+	if err != nil {
+		compareDummyPassword(password)
+		s.recordFailedLogin(r.Context(), email, "password")
 		writeJSONError(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		s.recordFailedLogin(r.Context(), email, "password")
 		writeJSONError(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	token, err := utils.GenerateJWT(user.ID, user.Email, user.Name, user.Role)
+	if _, err := s.Lockouts.ClearLockout(r.Context(), lockoutKey(email)); err != nil {
+		slog.ErrorContext(r.Context(), "Error clearing account lockout", "error", err)
+	}
+
+	if utils.PasswordNeedsRehash(user.Password) {
+		if hashedPassword, err := utils.HashPassword(password); err == nil {
+			if err := s.Credentials.UpdatePasswordHash(r.Context(), user.ID, hashedPassword); err != nil {
+				slog.ErrorContext(r.Context(), "Error upgrading password hash", "error", err)
+			}
+		}
+	}
+
+	if !user.Verified {
+		writeJSONError(w, "Email address not verified", http.StatusForbidden)
+		return
+	}
+
+	enabled, required, err := s.TwoFactor.GetTwoFactorStatus(r.Context(), user.ID)
 	if err != nil {
-		writeJSONError(w, "Failed to generate authentication token", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Error checking two-factor status", "error", err)
+		writeJSONError(w, "Failed to log in", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Login successful",
-		"token":   token,
-		"name":    user.Name,
-		"email":   user.Email,
-		"role":    user.Role,
-	})
+	if enabled || required {
+		challenge, err := utils.GenerateChallengeToken(user.ID, user.TokenVersion)
+		if err != nil {
+			writeJSONError(w, "Failed to generate challenge token", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":         "Two-factor authentication required",
+			"mfa_required":    true,
+			"mfa_enrolled":    enabled,
+			"challenge_token": challenge,
+		})
+		return
+	}
+
+	s.writeLoginResponse(w, r, user, nil)
 }
 
-func ValidateTokenHandler(w http.ResponseWriter, r *http.Request) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		writeJSONError(w, "Not logged in", http.StatusUnauthorized)
+func (s *Server) writeLoginResponse(w http.ResponseWriter, r *http.Request, user *models.User, extra map[string]interface{}) {
+	token, refreshToken, err := s.issueTokens(r, user)
+	if err != nil {
+		writeJSONError(w, "Failed to generate authentication token", http.StatusInternalServerError)
 		return
 	}
 
-	tokenParts := strings.Split(authHeader, " ")
-	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-		writeJSONError(w, "Invalid authorization format", http.StatusUnauthorized)
+	resp := map[string]interface{}{
+		"message":    "Login successful",
+		"expires_in": int(utils.AccessTokenTTL.Seconds()),
+		"name":       user.Name,
+		"email":      user.Email,
+		"role":       user.Role,
+		"roles":      user.RoleNames(),
+	}
+	for k, v := range extra {
+		resp[k] = v
+	}
+	if err := deliverTokens(w, resp, token, refreshToken); err != nil {
+		writeJSONError(w, "Failed to generate authentication token", http.StatusInternalServerError)
 		return
 	}
 
-	tokenString := tokenParts[1]
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) ValidateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	tokenString, _, err := accessToken(r)
+	if err != nil {
+		if errors.Is(err, errBadAuthorization) {
+			writeJSONError(w, "Invalid authorization format", http.StatusUnauthorized)
+			return
+		}
+		writeJSONError(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
 
 	claims, err := utils.ValidateJWT(tokenString)
 	if err != nil {
@@ -110,14 +220,64 @@ func ValidateTokenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
+	active, err := s.Sessions.IsAccessTokenActive(r.Context(), claims.UserID, claims.TokenVersion, claims.ID, claims.SessionID)
+	if err != nil || !active {
+		writeJSONError(w, "Not logged in", http.StatusUnauthorized)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message": "Session active",
 		"email":   claims.Email,
 		"name":    claims.Name,
 		"role":    claims.Role,
+		"roles":   claims.Roles,
 	})
 }
 
-func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	refreshToken, refreshFromCookie := refreshTokenFromRequest(r)
+	tokenString, accessFromCookie, _ := accessToken(r)
+	if (refreshFromCookie || accessFromCookie) && !validCSRF(r) {
+		writeJSONError(w, "Missing or invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	if refreshToken != "" {
+		if err := s.Sessions.RevokeRefreshToken(r.Context(), utils.HashToken(refreshToken)); err != nil {
+			slog.ErrorContext(r.Context(), "Error revoking refresh token", "error", err)
+			writeJSONError(w, "Failed to log out", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if tokenString != "" {
+		if claims, err := utils.ValidateJWT(tokenString); err == nil {
+			if err := s.Sessions.RevokeAccessToken(r.Context(), claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+				slog.ErrorContext(r.Context(), "Error revoking access token", "error", err)
+				writeJSONError(w, "Failed to log out", http.StatusInternalServerError)
+				return
+			}
+			if claims.SessionID != "" {
+				err := s.Sessions.RevokeSession(r.Context(), claims.UserID, claims.SessionID)
+				if err != nil && !errors.Is(err, store.ErrSessionNotFound) {
+					slog.ErrorContext(r.Context(), "Error revoking session", "error", err)
+					writeJSONError(w, "Failed to log out", http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+	}
+
+	if cookieConfig.Enabled {
+		clearAuthCookies(w)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out successfully"})
 }
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "correct horse battery staple"
+
+func newTestServer(t *testing.T) (*Server, *store.Memory) {
+	t.Helper()
+	if err := utils.LoadSigningKeys("", "test-secret-that-is-long-enough-for-hs256"); err != nil {
+		t.Fatal(err)
+	}
+	mem := store.NewMemory()
+	return NewServer(mem), mem
+}
+
+// addTestUser stores a verified attendee whose password is testPassword.
+func addTestUser(t *testing.T, mem *store.Memory, email string) int {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mem.AddUser(models.User{Name: "Alice", Email: email, Password: string(hash), Verified: true})
+}
+
+func postForm(handler http.HandlerFunc, path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func login(t *testing.T, srv *Server, email string) map[string]interface{} {
+	t.Helper()
+	rec := postForm(srv.LoginHandler, "/login", url.Values{"email": {email}, "password": {testPassword}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login status = %d, want 200 (%s)", rec.Code, rec.Body)
+	}
+	return decode(t, rec)
+}
+
+// validate calls ValidateTokenHandler with an access token and returns the
+// status code.
+func validate(srv *Server, token string) int {
+	req := httptest.NewRequest(http.MethodGet, "/validate_token", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	srv.ValidateTokenHandler(rec, req)
+	return rec.Code
+}
+
+func TestLoginLocksAccountAfterFailedAttempts(t *testing.T) {
+	srv, mem := newTestServer(t)
+	addTestUser(t, mem, "alice@example.com")
+
+	wrong := url.Values{"email": {"alice@example.com"}, "password": {"wrong password"}}
+	for i := 0; i < freeLoginAttempts; i++ {
+		if rec := postForm(srv.LoginHandler, "/login", wrong); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status = %d, want 401", i+1, rec.Code)
+		}
+	}
+
+	right := url.Values{"email": {"Alice@example.com "}, "password": {testPassword}}
+	rec := postForm(srv.LoginHandler, "/login", right)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("login while locked: status = %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("locked response has no Retry-After header")
+	}
+
+	if _, err := mem.ClearLockout(t.Context(), "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	login(t, srv, "alice@example.com")
+}
+
+func TestRefreshTokenReuseEndsSessions(t *testing.T) {
+	srv, mem := newTestServer(t)
+	addTestUser(t, mem, "alice@example.com")
+
+	tokens := login(t, srv, "alice@example.com")
+	oldRefresh := tokens["refresh_token"].(string)
+
+	rec := postForm(srv.RefreshTokenHandler, "/token/refresh", url.Values{"refresh_token": {oldRefresh}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh status = %d, want 200 (%s)", rec.Code, rec.Body)
+	}
+	refreshed := decode(t, rec)
+	if validate(srv, refreshed["token"].(string)) != http.StatusOK {
+		t.Fatal("refreshed access token is not accepted")
+	}
+
+	rec = postForm(srv.RefreshTokenHandler, "/token/refresh", url.Values{"refresh_token": {oldRefresh}})
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("reused refresh token: status = %d, want 401", rec.Code)
+	}
+
+	if validate(srv, refreshed["token"].(string)) != http.StatusUnauthorized {
+		t.Error("access token still works after refresh token reuse")
+	}
+	rec = postForm(srv.RefreshTokenHandler, "/token/refresh", url.Values{"refresh_token": {refreshed["refresh_token"].(string)}})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("newest refresh token: status = %d, want 401 after reuse", rec.Code)
+	}
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	srv, mem := newTestServer(t)
+	userID := addTestUser(t, mem, "alice@example.com")
+
+	tokens := login(t, srv, "alice@example.com")
+	token := tokens["token"].(string)
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader(url.Values{"refresh_token": {tokens["refresh_token"].(string)}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	srv.LogoutHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("logout status = %d, want 200 (%s)", rec.Code, rec.Body)
+	}
+
+	if validate(srv, token) != http.StatusUnauthorized {
+		t.Error("access token still works after logout")
+	}
+	sessions, err := mem.GetUserSessions(t.Context(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("%d sessions left after logout, want 0", len(sessions))
+	}
+}
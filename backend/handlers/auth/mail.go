@@ -0,0 +1,12 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+)
+
+var FrontendURL = "http://localhost:3000"
+
+func frontendLink(path, token string) string {
+	return strings.TrimRight(FrontendURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,484 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+const oidcLoginStateTTL = 10 * time.Minute
+
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// AuthURL overrides the discovered authorization endpoint, for when the
+	// browser reaches the identity provider under a different host than the
+	// backend does (e.g. inside docker compose).
+	AuthURL     string
+	Scopes      []string
+	GroupsClaim string
+	// RoleMapping maps identity provider groups onto role names.
+	RoleMapping map[string]string
+	// AutoCreate creates an attendee account for unknown identities whose
+	// verified email is not already taken, instead of rejecting them.
+	AutoCreate bool
+}
+
+type oidcClient struct {
+	config   OIDCConfig
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// ConfigureOIDC discovers the identity provider and enables the OIDC login
+// routes. It must run after the database is initialised.
+func (s *Server) ConfigureOIDC(ctx context.Context, cfg OIDCConfig) error {
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return errors.New("OIDC client ID and redirect URL are required")
+	}
+
+	for group, role := range cfg.RoleMapping {
+		if _, err := s.Permissions.GetRolePermissions(ctx, role); err != nil {
+			if errors.Is(err, store.ErrRoleNotFound) {
+				return fmt.Errorf("group %q maps to unknown role %q", group, role)
+			}
+			return err
+		}
+	}
+
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return fmt.Errorf("discovering %s: %w", cfg.IssuerURL, err)
+	}
+
+	endpoint := provider.Endpoint()
+	if cfg.AuthURL != "" {
+		endpoint.AuthURL = cfg.AuthURL
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+
+	s.oidc = &oidcClient{
+		config: cfg,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     endpoint,
+			Scopes:       scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}
+	return nil
+}
+
+// ParseRoleMapping reads "group=role,other-group=role" into a map.
+func ParseRoleMapping(s string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || role == "" {
+			return nil, fmt.Errorf("invalid role mapping %q", pair)
+		}
+		mapping[group] = role
+	}
+	return mapping, nil
+}
+
+func (s *Server) OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		writeJSONError(w, "Single sign-on is not configured", http.StatusNotFound)
+		return
+	}
+
+	state, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		writeJSONError(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		writeJSONError(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+	codeVerifier := oauth2.GenerateVerifier()
+
+	err = s.Identities.SaveOIDCLoginState(r.Context(), utils.HashToken(state), nonce, codeVerifier, time.Now().Add(oidcLoginStateTTL))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error saving OIDC login state", "error", err)
+		writeJSONError(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+
+	setOIDCStateCookie(w, state, oidcLoginStateTTL)
+	authURL := s.oidc.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallbackHandler finishes the login and sends the browser back to the
+// frontend. Tokens travel in the URL fragment so they never reach server logs,
+// or as cookies in cookie mode.
+func (s *Server) OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		writeJSONError(w, "Single sign-on is not configured", http.StatusNotFound)
+		return
+	}
+
+	// The state has to come back to the browser that started the login, or
+	// someone could complete their own login in a victim's browser.
+	q := r.URL.Query()
+	state := q.Get("state")
+	stateCookie, err := r.Cookie(oidcStateCookieName)
+	setOIDCStateCookie(w, "", -1)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(stateCookie.Value), []byte(state)) != 1 {
+		redirectOIDCResult(w, r, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	if idpErr := q.Get("error"); idpErr != "" {
+		slog.WarnContext(r.Context(), "OIDC login rejected by identity provider",
+			"error", idpErr, "description", q.Get("error_description"))
+		redirectOIDCResult(w, r, url.Values{"error": {"login_failed"}})
+		return
+	}
+
+	nonce, codeVerifier, err := s.Identities.ConsumeOIDCLoginState(r.Context(), utils.HashToken(state))
+	if err != nil {
+		if !errors.Is(err, store.ErrInvalidLoginState) {
+			slog.ErrorContext(r.Context(), "Error reading OIDC login state", "error", err)
+		}
+		redirectOIDCResult(w, r, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	identity, err := s.oidc.exchange(r.Context(), q.Get("code"), codeVerifier, nonce)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error completing OIDC login", "error", err)
+		redirectOIDCResult(w, r, url.Values{"error": {"login_failed"}})
+		return
+	}
+
+	userID, err := s.resolveUser(r.Context(), identity)
+	if err != nil {
+		if errors.Is(err, errNoLinkedAccount) {
+			// Once signed in, the user can attach the identity to their
+			// account from this browser, see LinkIdentityHandler.
+			linkToken, err := utils.GenerateIdentityLinkToken(identity.Issuer, identity.Subject, identity.Email)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Error generating identity link token", "error", err)
+			} else {
+				setOIDCLinkCookie(w, linkToken, utils.IdentityLinkTTL)
+			}
+			redirectOIDCResult(w, r, url.Values{"error": {"no_account"}})
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error resolving OIDC user", "error", err)
+		redirectOIDCResult(w, r, url.Values{"error": {"login_failed"}})
+		return
+	}
+
+	if err := s.syncRoles(r.Context(), userID, identity.Groups); err != nil {
+		slog.ErrorContext(r.Context(), "Error syncing roles from identity provider", "error", err)
+		redirectOIDCResult(w, r, url.Values{"error": {"login_failed"}})
+		return
+	}
+
+	user, err := s.Users.GetUser(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error loading OIDC user", "error", err)
+		redirectOIDCResult(w, r, url.Values{"error": {"login_failed"}})
+		return
+	}
+
+	if !user.Verified {
+		redirectOIDCResult(w, r, url.Values{"error": {"email_not_verified"}})
+		return
+	}
+
+	enabled, required, err := s.TwoFactor.GetTwoFactorStatus(r.Context(), user.ID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error checking two-factor status", "error", err)
+		redirectOIDCResult(w, r, url.Values{"error": {"login_failed"}})
+		return
+	}
+
+	if enabled || required {
+		challenge, err := utils.GenerateChallengeToken(user.ID, user.TokenVersion)
+		if err != nil {
+			redirectOIDCResult(w, r, url.Values{"error": {"login_failed"}})
+			return
+		}
+		redirectOIDCResult(w, r, url.Values{
+			"mfa_required":    {"true"},
+			"mfa_enrolled":    {fmt.Sprint(enabled)},
+			"challenge_token": {challenge},
+		})
+		return
+	}
+
+	token, refreshToken, err := s.issueTokens(r, &user)
+	if err != nil {
+		redirectOIDCResult(w, r, url.Values{"error": {"login_failed"}})
+		return
+	}
+
+	resp := map[string]interface{}{
+		"expires_in": int(utils.AccessTokenTTL.Seconds()),
+	}
+	if err := deliverTokens(w, resp, token, refreshToken); err != nil {
+		redirectOIDCResult(w, r, url.Values{"error": {"login_failed"}})
+		return
+	}
+
+	values := url.Values{}
+	for k, v := range resp {
+		values.Set(k, fmt.Sprint(v))
+	}
+	redirectOIDCResult(w, r, values)
+}
+
+func redirectOIDCResult(w http.ResponseWriter, r *http.Request, values url.Values) {
+	target := strings.TrimRight(FrontendURL, "/") + "/oidc/callback#" + values.Encode()
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+type oidcIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+var errNoLinkedAccount = errors.New("no account linked to this identity")
+
+func (c *oidcClient) exchange(ctx context.Context, code, codeVerifier, nonce string) (*oidcIdentity, error) {
+	if code == "" {
+		return nil, errors.New("missing authorization code")
+	}
+
+	token, err := c.oauth2.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchanging code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	idToken, err := c.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verifying id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     bool   `json:"email_verified"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := idToken.Claims(&raw); err != nil {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+	if name == "" {
+		name = claims.Email
+	}
+
+	return &oidcIdentity{
+		Issuer:        idToken.Issuer,
+		Subject:       idToken.Subject,
+		Email:         strings.ToLower(strings.TrimSpace(claims.Email)),
+		EmailVerified: claims.EmailVerified,
+		Name:          name,
+		Groups:        claimStrings(raw[c.config.GroupsClaim]),
+	}, nil
+}
+
+// resolveUser finds the account linked to an identity. An unlinked identity
+// gets a new attendee account when auto-creation is on and its verified email
+// is not taken. It is never attached to an existing account here: only the
+// signed-in owner can do that, through LinkIdentityHandler.
+func (s *Server) resolveUser(ctx context.Context, identity *oidcIdentity) (int, error) {
+	userID, err := s.Identities.GetUserIDByIdentity(ctx, identity.Issuer, identity.Subject)
+	switch {
+	case err == nil:
+		if err := s.Identities.LinkIdentity(ctx, userID, identity.Issuer, identity.Subject, identity.Email); err != nil {
+			return 0, err
+		}
+	case !errors.Is(err, store.ErrUserNotFound):
+		return 0, err
+	case !s.oidc.config.AutoCreate || identity.Email == "" || !identity.EmailVerified:
+		return 0, errNoLinkedAccount
+	default:
+		_, err := s.Credentials.GetUserIDByEmail(ctx, identity.Email)
+		switch {
+		case err == nil:
+			return 0, errNoLinkedAccount
+		case !errors.Is(err, store.ErrUserNotFound):
+			return 0, err
+		}
+		userID, err = s.createExternalUser(ctx, identity)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if identity.EmailVerified {
+		if err := s.Identities.MarkUserVerified(ctx, userID, identity.Email); err != nil {
+			return 0, err
+		}
+	}
+	return userID, nil
+}
+
+func (s *Server) createExternalUser(ctx context.Context, identity *oidcIdentity) (int, error) {
+	// The account can only sign in through the identity provider until the
+	// user sets a password via the reset flow.
+	password, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return 0, err
+	}
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return 0, err
+	}
+
+	user := models.User{
+		Name:     identity.Name,
+		Email:    identity.Email,
+		Role:     "attendee",
+		Verified: identity.EmailVerified,
+	}
+	return s.Identities.CreateExternalUser(ctx, user, hashedPassword, identity.Issuer, identity.Subject)
+}
+
+// LinkIdentityHandler attaches the identity from a failed single sign-on to
+// the signed-in user, who has to confirm it here. The link token is only read
+// from the cookie the sign-on set in this browser, and is used up either way.
+func (s *Server) LinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	linkCookie, err := r.Cookie(oidcLinkCookieName)
+	setOIDCLinkCookie(w, "", -1)
+	if err != nil || linkCookie.Value == "" {
+		writeJSONError(w, "No identity is waiting to be linked", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := utils.ValidateIdentityLinkToken(linkCookie.Value)
+	if err != nil {
+		writeJSONError(w, "Invalid or expired link token", http.StatusBadRequest)
+		return
+	}
+
+	err = s.Identities.AddUserIdentity(r.Context(), userID, claims.Issuer, claims.Subject, claims.Email)
+	if err != nil {
+		if errors.Is(err, store.ErrIdentityInUse) {
+			writeJSONError(w, "This identity is already linked to another account", http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error linking identity", "error", err)
+		writeJSONError(w, "Failed to link identity", http.StatusInternalServerError)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Identity linked", "user_id", userID, "issuer", claims.Issuer)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Identity linked"})
+}
+
+// syncRoles applies the group mapping. Only roles named in the mapping are
+// managed by the identity provider; other roles are kept as they are.
+func (s *Server) syncRoles(ctx context.Context, userID int, groups []string) error {
+	if len(s.oidc.config.RoleMapping) == 0 {
+		return nil
+	}
+
+	managedSet := map[string]bool{}
+	for _, role := range s.oidc.config.RoleMapping {
+		managedSet[role] = true
+	}
+	managed := make([]string, 0, len(managedSet))
+	for role := range managedSet {
+		managed = append(managed, role)
+	}
+	sort.Strings(managed)
+
+	var granted []string
+	for _, group := range groups {
+		if role, ok := s.oidc.config.RoleMapping[group]; ok {
+			granted = append(granted, role)
+		}
+	}
+
+	removed, err := s.Identities.SyncManagedRoles(ctx, userID, managed, granted)
+	if err != nil {
+		return err
+	}
+	if removed {
+		return s.Sessions.RevokeAllUserTokens(ctx, userID)
+	}
+	return nil
+}
+
+func claimStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"event_management/backend/store"
+	"event_management/backend/utils"
+)
+
+// linkIdentity posts to LinkIdentityHandler as the signed-in user, with the
+// given cookies and body.
+func linkIdentity(srv *Server, userID int, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, identitiesPath, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), utils.UserIDKey, userID))
+	rec := httptest.NewRecorder()
+	srv.LinkIdentityHandler(rec, req)
+	return rec
+}
+
+func TestLinkTokenOnlyWorksInTheSignOnBrowser(t *testing.T) {
+	srv, mem := newTestServer(t)
+	victimID := addTestUser(t, mem, "alice@example.com")
+	attackerID := addTestUser(t, mem, "mallory@example.com")
+
+	const issuer, subject = "https://idp.example.com", "alice-at-idp"
+	linkToken, err := utils.GenerateIdentityLinkToken(issuer, subject, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The token has leaked to another browser, which has no cookie for it.
+	rec := linkIdentity(srv, attackerID, `{"link_token":"`+linkToken+`"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("link without cookie: status = %d, want 400", rec.Code)
+	}
+	if _, err := mem.GetUserIDByIdentity(t.Context(), issuer, subject); !errors.Is(err, store.ErrUserNotFound) {
+		t.Fatalf("identity linked from another browser (err = %v)", err)
+	}
+
+	rec = linkIdentity(srv, victimID, "", &http.Cookie{Name: oidcLinkCookieName, Value: linkToken})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("link with cookie: status = %d, want 200 (%s)", rec.Code, rec.Body)
+	}
+	if userID, err := mem.GetUserIDByIdentity(t.Context(), issuer, subject); err != nil || userID != victimID {
+		t.Fatalf("identity linked to %d (err = %v), want %d", userID, err, victimID)
+	}
+
+	cleared := false
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == oidcLinkCookieName && c.MaxAge < 0 {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Error("link cookie not cleared after use")
+	}
+}
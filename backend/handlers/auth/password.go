@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"event_management/backend/mail"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const passwordResetTTL = time.Hour
+
+func (s *Server) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.allowLoginAttempt(w, r) {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	email := r.FormValue("email")
+	if email == "" {
+		writeJSONError(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	// The response never reveals whether the address belongs to an account,
+	// so the lookup and the email happen after it has been sent and cannot
+	// show in its timing either.
+	if ok, _ := s.resetEmailLimiter.Allow(lockoutKey(email)); ok {
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			if err := s.sendPasswordReset(ctx, email); err != nil {
+				slog.ErrorContext(ctx, "Error sending password reset", "error", err)
+			}
+		}()
+	} else {
+		slog.WarnContext(r.Context(), "Password reset emails rate limited")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If the address is registered, a password reset link has been sent",
+	})
+}
+
+func (s *Server) sendPasswordReset(ctx context.Context, email string) error {
+	userID, err := s.Credentials.GetUserIDByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(passwordResetTTL)
+	if err := s.Credentials.CreatePasswordResetToken(ctx, userID, utils.HashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	return mail.Send(mail.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"We received a request to reset your password.\n\nOpen the link below to choose a new one. It expires in %d minutes and can only be used once.\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			int(passwordResetTTL.Minutes()), frontendLink("/reset-password", token),
+		),
+	})
+}
+
+func (s *Server) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	token := r.FormValue("token")
+	password := r.FormValue("password")
+	if token == "" || password == "" {
+		writeJSONError(w, "Token and password are required", http.StatusBadRequest)
+		return
+	}
+
+	email, err := s.Credentials.GetPasswordResetEmail(r.Context(), utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidResetToken) {
+			writeJSONError(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error resetting password", "error", err)
+		writeJSONError(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := utils.CheckPasswordPolicy(password, email); err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		writeJSONError(w, "Error hashing password", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := s.Credentials.ResetPassword(r.Context(), utils.HashToken(token), hashedPassword); err != nil {
+		if errors.Is(err, store.ErrInvalidResetToken) {
+			writeJSONError(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error resetting password", "error", err)
+		writeJSONError(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password has been reset. Please log in again."})
+}
+
+// ChangePasswordHandler sets a new password for the logged-in user. Every
+// other session is ended; the caller gets fresh tokens so they stay signed in.
+func (s *Server) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.allowLoginAttempt(w, r) {
+		return
+	}
+
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	currentPassword := r.FormValue("current_password")
+	newPassword := r.FormValue("new_password")
+	if currentPassword == "" || newPassword == "" {
+		writeJSONError(w, "Current and new password are required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.Users.GetUser(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		writeJSONError(w, "Current password is incorrect", http.StatusUnauthorized)
+		return
+	}
+
+	if currentPassword == newPassword {
+		writeJSONError(w, "The new password must be different from the current one", http.StatusBadRequest)
+		return
+	}
+
+	if err := utils.CheckPasswordPolicy(newPassword, user.Email); err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		writeJSONError(w, "Error hashing password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.Credentials.ChangePassword(r.Context(), user.ID, hashedPassword); err != nil {
+		slog.ErrorContext(r.Context(), "Error changing password", "error", err)
+		writeJSONError(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	user, err = s.Users.GetUser(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error reloading user after password change", "error", err)
+		writeJSONError(w, "Password changed. Please log in again.", http.StatusInternalServerError)
+		return
+	}
+
+	token, refreshToken, err := s.issueTokens(r, &user)
+	if err != nil {
+		writeJSONError(w, "Password changed. Please log in again.", http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"message":    "Password changed",
+		"expires_in": int(utils.AccessTokenTTL.Seconds()),
+	}
+	if err := deliverTokens(w, resp, token, refreshToken); err != nil {
+		writeJSONError(w, "Password changed. Please log in again.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const permissionCacheTTL = 30 * time.Second
+
+// permissionCache keeps the role/permission mapping in memory. Edits made
+// through this process clear it immediately; other instances pick them up
+// within permissionCacheTTL.
+type permissionCache struct {
+	sync.RWMutex
+	byRole   map[string]map[string]bool
+	loadedAt time.Time
+}
+
+func (s *Server) rolePermissions(ctx context.Context) (map[string]map[string]bool, error) {
+	s.permissionCache.RLock()
+	byRole, loadedAt := s.permissionCache.byRole, s.permissionCache.loadedAt
+	s.permissionCache.RUnlock()
+
+	if byRole != nil && time.Since(loadedAt) < permissionCacheTTL {
+		return byRole, nil
+	}
+
+	mapping, err := s.Permissions.GetRolePermissionMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byRole = make(map[string]map[string]bool, len(mapping))
+	for role, permissions := range mapping {
+		set := make(map[string]bool, len(permissions))
+		for _, p := range permissions {
+			set[p] = true
+		}
+		byRole[role] = set
+	}
+
+	s.permissionCache.Lock()
+	s.permissionCache.byRole = byRole
+	s.permissionCache.loadedAt = time.Now()
+	s.permissionCache.Unlock()
+
+	return byRole, nil
+}
+
+func (s *Server) invalidatePermissionCache() {
+	s.permissionCache.Lock()
+	s.permissionCache.byRole = nil
+	s.permissionCache.Unlock()
+}
+
+func (s *Server) hasPermission(r *http.Request, permission string) (bool, error) {
+	byRole, err := s.rolePermissions(r.Context())
+	if err != nil {
+		return false, err
+	}
+
+	// API keys only reach what their scopes allow, on top of the owner's roles.
+	if scopes, ok := r.Context().Value(utils.APIKeyScopesKey).([]string); ok && !containsString(scopes, permission) {
+		return false, nil
+	}
+
+	roles, _ := r.Context().Value(utils.UserRolesKey).([]string)
+	for _, role := range roles {
+		if byRole[role][permission] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RequirePermission only lets a request through when one of the caller's roles
+// grants the permission. It must run after JWTMiddleware.
+func (s *Server) RequirePermission(permission string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return permissionGate{server: s, permission: permission, next: next}
+	}
+}
+
+// permissionGate is the handler RequirePermission returns. It is also where
+// an API key request gets its user, so keys only reach routes that declare a
+// permission.
+type permissionGate struct {
+	server     *Server
+	permission string
+	next       http.Handler
+}
+
+func (g permissionGate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if owner, ok := r.Context().Value(apiKeyOwnerKey).(*models.APIKeyOwner); ok {
+		r = r.WithContext(withAPIKeyOwner(r.Context(), owner))
+	}
+
+	allowed, err := g.server.hasPermission(r, g.permission)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error checking permission", "permission", g.permission, "error", err)
+		writeJSONError(w, "Error checking permissions", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		writeJSONError(w, "Forbidden. Missing permission "+g.permission, http.StatusForbidden)
+		return
+	}
+	g.next.ServeHTTP(w, r)
+}
+
+func (s *Server) GetPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	permissions, err := s.Permissions.GetAllPermissions(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error retrieving permissions", "error", err)
+		writeJSONError(w, "Failed to retrieve permissions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(permissions)
+}
+
+func (s *Server) GetRolePermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	role := mux.Vars(r)["name"]
+
+	permissions, err := s.Permissions.GetRolePermissions(r.Context(), role)
+	if err != nil {
+		if errors.Is(err, store.ErrRoleNotFound) {
+			writeJSONError(w, "Role not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error retrieving role permissions", "error", err)
+		writeJSONError(w, "Failed to retrieve role permissions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"role":        role,
+		"permissions": permissions,
+	})
+}
+
+func (s *Server) SetRolePermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	role := mux.Vars(r)["name"]
+
+	var req struct {
+		Permissions []string `json:"permissions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Permissions == nil {
+		writeJSONError(w, "permissions must be a list", http.StatusBadRequest)
+		return
+	}
+
+	if role == "admin" && !containsString(req.Permissions, "permission:manage") {
+		writeJSONError(w, "The admin role must keep permission:manage", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Permissions.SetRolePermissions(r.Context(), role, req.Permissions); err != nil {
+		switch {
+		case errors.Is(err, store.ErrRoleNotFound):
+			writeJSONError(w, "Role not found", http.StatusNotFound)
+		case errors.Is(err, store.ErrUnknownPermission):
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+		default:
+			slog.ErrorContext(r.Context(), "Error updating role permissions", "error", err)
+			writeJSONError(w, "Failed to update role permissions", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.invalidatePermissionCache()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"role":        role,
+		"permissions": req.Permissions,
+	})
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
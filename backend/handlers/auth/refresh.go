@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// issueTokens starts a new session for the request's device and returns its
+// access and refresh tokens.
+func (s *Server) issueTokens(r *http.Request, user *models.User) (string, string, error) {
+	session, err := newSessionInfo(r)
+	if err != nil {
+		return "", "", err
+	}
+
+	token, err := utils.GenerateJWT(user.ID, user.Email, user.Name, user.RoleNames(), user.TokenVersion, session.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	expiresAt := time.Now().Add(utils.RefreshTokenTTL)
+	if err := s.Sessions.CreateRefreshToken(r.Context(), user.ID, utils.HashToken(refreshToken), expiresAt, session); err != nil {
+		return "", "", err
+	}
+
+	return token, refreshToken, nil
+}
+
+func newSessionInfo(r *http.Request) (models.SessionInfo, error) {
+	id, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return models.SessionInfo{}, err
+	}
+	return models.SessionInfo{
+		ID:        id,
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+	}, nil
+}
+
+func (s *Server) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	refreshToken, fromCookie := refreshTokenFromRequest(r)
+	if refreshToken == "" {
+		writeJSONError(w, "Refresh token is required", http.StatusBadRequest)
+		return
+	}
+	if fromCookie && !validCSRF(r) {
+		writeJSONError(w, "Missing or invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	newRefreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		writeJSONError(w, "Failed to generate refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	session, err := newSessionInfo(r)
+	if err != nil {
+		writeJSONError(w, "Failed to generate refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	userID, sessionID, err := s.Sessions.RotateRefreshToken(r.Context(),
+		utils.HashToken(refreshToken),
+		utils.HashToken(newRefreshToken),
+		time.Now().Add(utils.RefreshTokenTTL),
+		session,
+	)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidRefreshToken) || errors.Is(err, store.ErrRefreshTokenReused) {
+			writeJSONError(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error rotating refresh token", "error", err)
+		writeJSONError(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := s.Users.GetUser(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := utils.GenerateJWT(user.ID, user.Email, user.Name, user.RoleNames(), user.TokenVersion, sessionID)
+	if err != nil {
+		writeJSONError(w, "Failed to generate authentication token", http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"message":    "Token refreshed",
+		"expires_in": int(utils.AccessTokenTTL.Seconds()),
+	}
+	if err := deliverTokens(w, resp, token, newRefreshToken); err != nil {
+		writeJSONError(w, "Failed to generate authentication token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
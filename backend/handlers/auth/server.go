@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"event_management/backend/store"
+	"event_management/backend/utils"
+)
+
+// Stores is everything the auth handlers persist. database.SQLStore and
+// store.Memory both implement it.
+type Stores interface {
+	store.UserStore
+	store.CredentialStore
+	store.LockoutStore
+	store.SessionStore
+	store.TwoFactorStore
+	store.APIKeyStore
+	store.PermissionStore
+	store.InvitationStore
+	store.ImpersonationStore
+	store.IdentityStore
+}
+
+// Server holds the stores and the in-process state of the auth handlers.
+type Server struct {
+	Users          store.UserStore
+	Credentials    store.CredentialStore
+	Lockouts       store.LockoutStore
+	Sessions       store.SessionStore
+	TwoFactor      store.TwoFactorStore
+	APIKeys        store.APIKeyStore
+	Permissions    store.PermissionStore
+	Invitations    store.InvitationStore
+	Impersonations store.ImpersonationStore
+	Identities     store.IdentityStore
+
+	// oidc is set by ConfigureOIDC; single sign-on is off while it is nil.
+	oidc *oidcClient
+
+	permissionCache permissionCache
+	// loginLimiter throttles the login and account recovery endpoints per
+	// client IP. resetEmailLimiter and verificationEmailLimiter cap the
+	// emails one address can receive, whoever asks for them.
+	loginLimiter             *utils.RateLimiter
+	resetEmailLimiter        *utils.RateLimiter
+	verificationEmailLimiter *utils.RateLimiter
+}
+
+func NewServer(stores Stores) *Server {
+	return &Server{
+		Users:          stores,
+		Credentials:    stores,
+		Lockouts:       stores,
+		Sessions:       stores,
+		TwoFactor:      stores,
+		APIKeys:        stores,
+		Permissions:    stores,
+		Invitations:    stores,
+		Impersonations: stores,
+		Identities:     stores,
+
+		loginLimiter:             utils.NewRateLimiter(20, 10),
+		resetEmailLimiter:        utils.NewRateLimiter(0.2, 3),
+		verificationEmailLimiter: utils.NewRateLimiter(0.2, 3),
+	}
+}
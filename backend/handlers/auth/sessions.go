@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (s *Server) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.writeSessions(w, r, userID)
+}
+
+func (s *Server) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.revokeSession(w, r, userID, mux.Vars(r)["sid"])
+}
+
+func (s *Server) GetUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	s.writeSessions(w, r, userID)
+}
+
+func (s *Server) RevokeUserSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	s.revokeSession(w, r, userID, mux.Vars(r)["sid"])
+}
+
+// RevokeAllUserSessionsHandler signs a user out everywhere, API keys
+// included.
+func (s *Server) RevokeAllUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Sessions.RevokeAllUserTokens(r.Context(), userID); err != nil {
+		slog.ErrorContext(r.Context(), "Error revoking sessions", "error", err)
+		writeJSONError(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+	if err := s.APIKeys.RevokeUserAPIKeys(r.Context(), userID); err != nil {
+		slog.ErrorContext(r.Context(), "Error revoking API keys", "error", err)
+		writeJSONError(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "All sessions revoked"})
+}
+
+func (s *Server) writeSessions(w http.ResponseWriter, r *http.Request, userID int) {
+	sessions, err := s.Sessions.GetUserSessions(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error retrieving sessions", "error", err)
+		writeJSONError(w, "Failed to retrieve sessions", http.StatusInternalServerError)
+		return
+	}
+
+	currentUserID, _ := r.Context().Value(utils.UserIDKey).(int)
+	currentSession, _ := r.Context().Value(utils.SessionIDKey).(string)
+	for i := range sessions {
+		sessions[i].Current = currentUserID == userID && sessions[i].ID == currentSession
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+func (s *Server) revokeSession(w http.ResponseWriter, r *http.Request, userID int, sessionID string) {
+	if err := s.Sessions.RevokeSession(r.Context(), userID, sessionID); err != nil {
+		if errors.Is(err, store.ErrSessionNotFound) {
+			writeJSONError(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error revoking session", "error", err)
+		writeJSONError(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Session revoked"})
+}
@@ -2,16 +2,15 @@ package auth
 
 import (
 	"encoding/json"
-	"event_management/backend/database"
 	"event_management/backend/models"
+	"event_management/backend/utils"
+	"log/slog"
 
 	"net/http"
 	"strings"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
-func SignupHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) SignupHandler(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		writeJSONError(w, "Invalid form data", http.StatusBadRequest)
 		return
@@ -23,17 +22,31 @@ func SignupHandler(w http.ResponseWriter, r *http.Request) {
 	phone := r.FormValue("phone")
 	role := strings.ToLower(r.FormValue("role"))
 
-	if name == "" || email == "" || password == "" || phone == "" || role == "" {
+	if name == "" || email == "" || password == "" || phone == "" {
 		writeJSONError(w, "All fields are required", http.StatusBadRequest)
 		return
 	}
 
-	if role != "admin" && role != "organiser" && role != "attendee" {
+	if role == "" {
+		role = "attendee"
+	}
+
+	if role == "admin" || role == "organiser" {
+		writeJSONError(w, "Admin and organiser accounts require an invitation", http.StatusForbidden)
+		return
+	}
+
+	if role != "attendee" {
 		writeJSONError(w, "Invalid role specified", http.StatusBadRequest)
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err := utils.CheckPasswordPolicy(password, email); err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
 		writeJSONError(w, "Error hashing password", http.StatusInternalServerError)
 		return
@@ -46,16 +59,20 @@ func SignupHandler(w http.ResponseWriter, r *http.Request) {
 		Role:  role,
 	}
 
-	err = database.CreateUser(user, hashedPassword)
+	userID, err := s.Credentials.CreateUser(r.Context(), user, hashedPassword)
 	if err != nil {
 		writeJSONError(w, "Email already registered or DB error", http.StatusBadRequest)
 		return
 	}
 
+	if err := s.sendVerificationEmail(r.Context(), userID, email); err != nil {
+		slog.ErrorContext(r.Context(), "Error sending verification email", "error", err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(models.AuthResponse{
-		Message: "Signup successful!",
+		Message: "Signup successful! Please check your email to verify your address.",
 		Name:    name,
 		Email:   email,
 		Role:    role,
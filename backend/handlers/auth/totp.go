@@ -0,0 +1,477 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpIssuer        = "EventEase"
+	recoveryCodeCount = 10
+)
+
+var errInvalidChallenge = errors.New("invalid or expired challenge token")
+
+// validateChallengeToken checks a challenge token like JWTMiddleware checks
+// an access token, so it stops working once used or once the user's sessions
+// are revoked.
+func (s *Server) validateChallengeToken(ctx context.Context, token string) (*utils.Claims, error) {
+	claims, err := utils.ValidateChallengeToken(token)
+	if err != nil {
+		return nil, errInvalidChallenge
+	}
+
+	active, err := s.Sessions.IsAccessTokenActive(ctx, claims.UserID, claims.TokenVersion, claims.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, errInvalidChallenge
+	}
+	return claims, nil
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+func (s *Server) verifySecondFactor(ctx context.Context, userID int, state *models.TOTPState, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		if !state.Enabled {
+			return false, nil
+		}
+		return s.TwoFactor.UseRecoveryCode(ctx, userID, utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode)))
+	}
+
+	step, ok := utils.ValidateTOTP(state.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return s.TwoFactor.MarkTOTPStepUsed(ctx, userID, step)
+}
+
+func (s *Server) startEnrolment(ctx context.Context, userID int, email string) (map[string]interface{}, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.TwoFactor.SavePendingTOTPSecret(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"message":          "Scan the provisioning URI with an authenticator app, then confirm with a code",
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(secret, totpIssuer, email),
+	}, nil
+}
+
+func (s *Server) LoginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.allowLoginAttempt(w, r) {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	code := r.FormValue("code")
+	recoveryCode := r.FormValue("recovery_code")
+	if code == "" && recoveryCode == "" {
+		writeJSONError(w, "Code or recovery code is required", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := s.validateChallengeToken(r.Context(), r.FormValue("challenge_token"))
+	if err != nil {
+		if errors.Is(err, errInvalidChallenge) {
+			writeJSONError(w, "Invalid or expired challenge token", http.StatusUnauthorized)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error checking challenge token", "error", err)
+		writeJSONError(w, "Error verifying token", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := s.Users.GetUser(r.Context(), claims.UserID)
+	if err != nil {
+		writeJSONError(w, "Invalid or expired challenge token", http.StatusUnauthorized)
+		return
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords, which
+	// also stops further guesses with a challenge token already issued.
+	remaining, err := s.Lockouts.GetLockoutRemaining(r.Context(), lockoutKey(user.Email))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error checking account lockout", "error", err)
+		writeJSONError(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if remaining > 0 {
+		writeTooManyRequests(w, "Too many failed login attempts. Please try again later.", remaining)
+		return
+	}
+
+	state, err := s.TwoFactor.GetTOTPState(r.Context(), claims.UserID)
+	if err != nil {
+		if errors.Is(err, store.ErrTwoFactorNotEnrolled) {
+			writeJSONError(w, "Two-factor enrolment has not been started", http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error loading TOTP state", "error", err)
+		writeJSONError(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+
+	ok, err := s.verifySecondFactor(r.Context(), claims.UserID, state, code, recoveryCode)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error verifying second factor", "error", err)
+		writeJSONError(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		s.recordFailedLogin(r.Context(), user.Email, "second_factor")
+		writeJSONError(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := s.Lockouts.ClearLockout(r.Context(), lockoutKey(user.Email)); err != nil {
+		slog.ErrorContext(r.Context(), "Error clearing account lockout", "error", err)
+	}
+
+	// A challenge token completes one login only.
+	if err := s.Sessions.RevokeAccessToken(r.Context(), claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+		slog.ErrorContext(r.Context(), "Error revoking challenge token", "error", err)
+		writeJSONError(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+
+	extra := map[string]interface{}{}
+	if !state.Enabled {
+		codes, hashes, err := newRecoveryCodes()
+		if err != nil {
+			writeJSONError(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+			return
+		}
+		if err := s.TwoFactor.EnableTOTP(r.Context(), claims.UserID, hashes); err != nil {
+			slog.ErrorContext(r.Context(), "Error enabling TOTP", "error", err)
+			writeJSONError(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+			return
+		}
+		extra["recovery_codes"] = codes
+	}
+
+	s.writeLoginResponse(w, r, &user, extra)
+}
+
+// LoginTwoFactorEnrollHandler lets a user whose role requires 2FA enrol with
+// the challenge token, since they cannot obtain an access token before that.
+func (s *Server) LoginTwoFactorEnrollHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := s.validateChallengeToken(r.Context(), r.FormValue("challenge_token"))
+	if err != nil {
+		if errors.Is(err, errInvalidChallenge) {
+			writeJSONError(w, "Invalid or expired challenge token", http.StatusUnauthorized)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error checking challenge token", "error", err)
+		writeJSONError(w, "Error verifying token", http.StatusInternalServerError)
+		return
+	}
+
+	enabled, _, err := s.TwoFactor.GetTwoFactorStatus(r.Context(), claims.UserID)
+	if err != nil {
+		writeJSONError(w, "Failed to start enrolment", http.StatusInternalServerError)
+		return
+	}
+	if enabled {
+		writeJSONError(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	user, err := s.Users.GetUser(r.Context(), claims.UserID)
+	if err != nil {
+		writeJSONError(w, "Invalid or expired challenge token", http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := s.startEnrolment(r.Context(), user.ID, user.Email)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error starting TOTP enrolment", "error", err)
+		writeJSONError(w, "Failed to start enrolment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) EnrollTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	email, _ := r.Context().Value(utils.UserEmailKey).(string)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	enabled, _, err := s.TwoFactor.GetTwoFactorStatus(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, "Failed to start enrolment", http.StatusInternalServerError)
+		return
+	}
+	if enabled {
+		writeJSONError(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	resp, err := s.startEnrolment(r.Context(), userID, email)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error starting TOTP enrolment", "error", err)
+		writeJSONError(w, "Failed to start enrolment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) ConfirmTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		writeJSONError(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	state, err := s.TwoFactor.GetTOTPState(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, store.ErrTwoFactorNotEnrolled) {
+			writeJSONError(w, "Two-factor enrolment has not been started", http.StatusBadRequest)
+			return
+		}
+		writeJSONError(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if state.Enabled {
+		writeJSONError(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	valid, err := s.verifySecondFactor(r.Context(), userID, state, req.Code, "")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error verifying TOTP code", "error", err)
+		writeJSONError(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		writeJSONError(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		writeJSONError(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+	if err := s.TwoFactor.EnableTOTP(r.Context(), userID, hashes); err != nil {
+		slog.ErrorContext(r.Context(), "Error enabling TOTP", "error", err)
+		writeJSONError(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+func (s *Server) DisableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Password == "" || (req.Code == "" && req.RecoveryCode == "") {
+		writeJSONError(w, "Password and code are required", http.StatusBadRequest)
+		return
+	}
+
+	enabled, required, err := s.TwoFactor.GetTwoFactorStatus(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if !enabled {
+		writeJSONError(w, "Two-factor authentication is not enabled", http.StatusBadRequest)
+		return
+	}
+	if required {
+		writeJSONError(w, "Two-factor authentication is required for your role", http.StatusForbidden)
+		return
+	}
+
+	user, err := s.Users.GetUser(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		writeJSONError(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	state, err := s.TwoFactor.GetTOTPState(r.Context(), userID)
+	if err != nil {
+		writeJSONError(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	valid, err := s.verifySecondFactor(r.Context(), userID, state, req.Code, req.RecoveryCode)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error verifying second factor", "error", err)
+		writeJSONError(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		writeJSONError(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.TwoFactor.DisableTOTP(r.Context(), userID); err != nil {
+		slog.ErrorContext(r.Context(), "Error disabling TOTP", "error", err)
+		writeJSONError(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+func (s *Server) RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		writeJSONError(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	state, err := s.TwoFactor.GetTOTPState(r.Context(), userID)
+	if err != nil || !state.Enabled {
+		writeJSONError(w, "Two-factor authentication is not enabled", http.StatusBadRequest)
+		return
+	}
+
+	valid, err := s.verifySecondFactor(r.Context(), userID, state, req.Code, "")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error verifying TOTP code", "error", err)
+		writeJSONError(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		writeJSONError(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		writeJSONError(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+	if err := s.TwoFactor.ReplaceRecoveryCodes(r.Context(), userID, hashes); err != nil {
+		slog.ErrorContext(r.Context(), "Error replacing recovery codes", "error", err)
+		writeJSONError(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Recovery codes regenerated",
+		"recovery_codes": codes,
+	})
+}
+
+func (s *Server) GetTwoFactorPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	policies, err := s.TwoFactor.GetRoleTwoFactorPolicies(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error retrieving two-factor policies", "error", err)
+		writeJSONError(w, "Failed to retrieve two-factor policies", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policies)
+}
+
+func (s *Server) SetTwoFactorPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	role := mux.Vars(r)["name"]
+
+	var req struct {
+		Required *bool `json:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Required == nil {
+		writeJSONError(w, "required must be true or false", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.TwoFactor.SetRoleTwoFactorRequired(r.Context(), role, *req.Required); err != nil {
+		if errors.Is(err, store.ErrRoleNotFound) {
+			writeJSONError(w, "Role not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error updating two-factor policy", "error", err)
+		writeJSONError(w, "Failed to update two-factor policy", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.RoleTwoFactorPolicy{Role: role, Required: *req.Required})
+}
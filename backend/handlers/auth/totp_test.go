@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"event_management/backend/store"
+	"event_management/backend/utils"
+)
+
+// enableTwoFactor finishes a TOTP enrolment for the user and returns their
+// recovery codes.
+func enableTwoFactor(t *testing.T, mem *store.Memory, userID int) []string {
+	t.Helper()
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.SavePendingTOTPSecret(t.Context(), userID, secret); err != nil {
+		t.Fatal(err)
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.EnableTOTP(t.Context(), userID, hashes); err != nil {
+		t.Fatal(err)
+	}
+	return codes
+}
+
+func secondFactor(srv *Server, challenge, recoveryCode string) int {
+	form := url.Values{"challenge_token": {challenge}, "recovery_code": {recoveryCode}}
+	return postForm(srv.LoginTwoFactorHandler, "/login/2fa", form).Code
+}
+
+func TestChallengeTokenCompletesOneLogin(t *testing.T) {
+	srv, mem := newTestServer(t)
+	userID := addTestUser(t, mem, "alice@example.com")
+	codes := enableTwoFactor(t, mem, userID)
+
+	challenge := login(t, srv, "alice@example.com")["challenge_token"].(string)
+	if status := secondFactor(srv, challenge, codes[0]); status != http.StatusOK {
+		t.Fatalf("second factor: status = %d, want 200", status)
+	}
+	if status := secondFactor(srv, challenge, codes[1]); status != http.StatusUnauthorized {
+		t.Errorf("reused challenge: status = %d, want 401", status)
+	}
+}
+
+func TestChallengeTokenDiesWithTheSessions(t *testing.T) {
+	srv, mem := newTestServer(t)
+	userID := addTestUser(t, mem, "alice@example.com")
+	codes := enableTwoFactor(t, mem, userID)
+
+	challenge := login(t, srv, "alice@example.com")["challenge_token"].(string)
+	if err := mem.RevokeAllUserTokens(t.Context(), userID); err != nil {
+		t.Fatal(err)
+	}
+	if status := secondFactor(srv, challenge, codes[0]); status != http.StatusUnauthorized {
+		t.Errorf("challenge after revoking sessions: status = %d, want 401", status)
+	}
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"event_management/backend/mail"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	emailVerificationTTL   = 24 * time.Hour
+	verificationCooldown   = time.Minute
+	verificationWindow     = time.Hour
+	maxVerificationsWindow = 5
+)
+
+func (s *Server) sendVerificationEmail(ctx context.Context, userID int, email string) error {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(emailVerificationTTL)
+	if err := s.Credentials.CreateEmailVerificationToken(ctx, userID, utils.HashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	return mail.Send(mail.Message{
+		To:      email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf(
+			"Welcome to EventEase!\n\nPlease confirm your email address by opening the link below. It expires in %d hours.\n\n%s\n",
+			int(emailVerificationTTL.Hours()), frontendLink("/verify-email", token),
+		),
+	})
+}
+
+func (s *Server) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	token := r.FormValue("token")
+	if token == "" {
+		writeJSONError(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.Credentials.VerifyEmail(r.Context(), utils.HashToken(token)); err != nil {
+		if errors.Is(err, store.ErrInvalidVerificationToken) {
+			writeJSONError(w, "Invalid or expired verification token", http.StatusBadRequest)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Error verifying email", "error", err)
+		writeJSONError(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email verified. You can now log in."})
+}
+
+func (s *Server) ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.allowLoginAttempt(w, r) {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	email := r.FormValue("email")
+	if email == "" {
+		writeJSONError(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	// As with password resets, neither the response nor its timing shows
+	// whether the address has an unverified account or was throttled.
+	if ok, _ := s.verificationEmailLimiter.Allow(lockoutKey(email)); ok {
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			if err := s.resendVerificationEmail(ctx, email); err != nil {
+				slog.ErrorContext(ctx, "Error sending verification email", "error", err)
+			}
+		}()
+	} else {
+		slog.WarnContext(r.Context(), "Verification emails rate limited")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If the address is registered and unverified, a verification link has been sent",
+	})
+}
+
+// resendVerificationEmail sends a new link to an unverified account, at most
+// once per verificationCooldown and maxVerificationsWindow times per
+// verificationWindow.
+func (s *Server) resendVerificationEmail(ctx context.Context, email string) error {
+	userID, err := s.Credentials.GetUnverifiedUserIDByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	now := time.Now()
+	recent, inWindow, err := s.Credentials.CountVerificationEmailsSince(ctx, userID, now.Add(-verificationCooldown), now.Add(-verificationWindow))
+	if err != nil {
+		return err
+	}
+	if recent > 0 || inWindow >= maxVerificationsWindow {
+		slog.WarnContext(ctx, "Verification emails throttled", "user_id", userID)
+		return nil
+	}
+
+	return s.sendVerificationEmail(ctx, userID, email)
+}
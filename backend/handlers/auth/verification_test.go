@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"event_management/backend/mail"
+	"event_management/backend/models"
+)
+
+type chanSender chan mail.Message
+
+func (c chanSender) Send(msg mail.Message) error {
+	c <- msg
+	return nil
+}
+
+func TestResendVerificationDoesNotRevealAccounts(t *testing.T) {
+	srv, mem := newTestServer(t)
+	addTestUser(t, mem, "verified@example.com")
+	mem.AddUser(models.User{Name: "Bob", Email: "bob@example.com"})
+
+	sent := make(chanSender, 10)
+	prev := mail.DefaultSender
+	mail.DefaultSender = sent
+	t.Cleanup(func() { mail.DefaultSender = prev })
+
+	var want string
+	for _, email := range []string{"nobody@example.com", "verified@example.com", "bob@example.com", "bob@example.com", "bob@example.com", "bob@example.com"} {
+		rec := postForm(srv.ResendVerificationHandler, "/verify-email/resend", url.Values{"email": {email}})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", email, rec.Code)
+		}
+		if want == "" {
+			want = rec.Body.String()
+		} else if rec.Body.String() != want {
+			t.Fatalf("%s: body = %q, want %q", email, rec.Body, want)
+		}
+	}
+
+	select {
+	case msg := <-sent:
+		if msg.To != "bob@example.com" {
+			t.Errorf("verification email sent to %s", msg.To)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no verification email sent")
+	}
+}
@@ -2,20 +2,20 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
-	"event_management/backend/database"
+	"event_management/backend/metrics"
 	"event_management/backend/models"
+	"event_management/backend/store"
 	"event_management/backend/utils"
 
 	"github.com/gorilla/mux"
 )
 
-
-
 type eventRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
@@ -24,11 +24,11 @@ type eventRequest struct {
 	Capacity    int    `json:"capacity"`
 }
 
-func GetEventsHandler(w http.ResponseWriter, r *http.Request) {
-	events, err := database.GetAllEvents()
+func (s *Server) GetEventsHandler(w http.ResponseWriter, r *http.Request) {
+	events, err := s.Events.ListEvents(r.Context())
 	if err != nil {
 		http.Error(w, "Error retrieving events", http.StatusInternalServerError)
-		log.Printf("Error retrieving events: %v", err)
+		slog.ErrorContext(r.Context(), "Error retrieving events", "error", err)
 		return
 	}
 
@@ -37,11 +37,20 @@ func GetEventsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(events)
 }
 
-func RegisterForEventHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) RegisterForEventHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(utils.UserIDKey).(int)
-	userRole, _ := r.Context().Value(utils.UserRoleKey).(string)
-	if !ok || userRole != "attendee" {
-		http.Error(w, "Only attendees can register", http.StatusForbidden)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	verified, err := s.Users.IsUserVerified(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Error checking email verification", http.StatusInternalServerError)
+		return
+	}
+	if !verified {
+		http.Error(w, "Email address not verified", http.StatusForbidden)
 		return
 	}
 
@@ -58,12 +67,12 @@ func RegisterForEventHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := database.GetEventByID(eventID); err != nil {
+	if _, err := s.Events.GetEvent(r.Context(), eventID); err != nil {
 		http.Error(w, "Event not found", http.StatusNotFound)
 		return
 	}
 
-	isRegistered, err := database.IsUserRegisteredForEvent(userID, eventID)
+	isRegistered, err := s.Registrations.IsRegistered(r.Context(), userID, eventID)
 	if err != nil {
 		http.Error(w, "Error checking registration status", http.StatusInternalServerError)
 		return
@@ -81,25 +90,27 @@ func RegisterForEventHandler(w http.ResponseWriter, r *http.Request) {
 		Status:           "confirmed",
 	}
 
-	registrationID, err := database.CreateRegistration(reg)
+	registrationID, err := s.Registrations.CreateRegistration(r.Context(), reg)
 	if err != nil {
-		if err.Error() == "event is full" {
+		if errors.Is(err, store.ErrEventFull) {
+			metrics.EventFullRejections.Inc()
 			http.Error(w, "Event at full capacity", http.StatusBadRequest)
 		} else {
 			http.Error(w, "Error creating registration", http.StatusInternalServerError)
-			log.Printf("Error creating registration: %v", err)
+			slog.ErrorContext(r.Context(), "Error creating registration", "error", err)
 		}
 		return
 	}
 
 	reg.ID = registrationID
+	metrics.RegistrationsCreated.Inc()
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(reg)
 }
 
-func CancelRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CancelRegistrationHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(utils.UserIDKey).(int)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -119,7 +130,7 @@ func CancelRegistrationHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isOwner, err := database.IsRegistrationOwner(regID, userID)
+	isOwner, err := s.Registrations.IsRegistrationOwner(r.Context(), regID, userID)
 	if err != nil {
 		http.Error(w, "Error verifying ownership", http.StatusInternalServerError)
 		return
@@ -129,28 +140,28 @@ func CancelRegistrationHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := database.CancelRegistration(regID); err != nil {
+	if err := s.Registrations.CancelRegistration(r.Context(), regID); err != nil {
 		http.Error(w, "Error cancelling registration", http.StatusInternalServerError)
-		log.Printf("Error cancelling registration: %v", err)
+		slog.ErrorContext(r.Context(), "Error cancelling registration", "error", err)
 		return
 	}
+	metrics.RegistrationsCancelled.Inc()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Registration cancelled"})
 }
 
-func GetOrganizerEventsHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetOrganizerEventsHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(utils.UserIDKey).(int)
-	userRole, _ := r.Context().Value(utils.UserRoleKey).(string)
-	if !ok || userRole != "organiser" {
-		http.Error(w, "Only organisers can view their events", http.StatusForbidden)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	events, err := database.GetEventsByOrganizerID(userID)
+	events, err := s.Events.ListEventsByOrganiser(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Error retrieving events", http.StatusInternalServerError)
-		log.Printf("Error retrieving events: %v", err)
+		slog.ErrorContext(r.Context(), "Error retrieving events", "error", err)
 		return
 	}
 
@@ -158,11 +169,10 @@ func GetOrganizerEventsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(events)
 }
 
-func GetEventRegistrationsHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetEventRegistrationsHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(utils.UserIDKey).(int)
-	userRole, _ := r.Context().Value(utils.UserRoleKey).(string)
-	if !ok || userRole != "organiser" {
-		http.Error(w, "Only organisers can view registrations", http.StatusForbidden)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -179,7 +189,11 @@ func GetEventRegistrationsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isOwner, err := database.IsEventOrganizer(eventID, userID)
+	isOwner, err := s.Events.IsEventOrganiser(r.Context(), eventID, userID)
+	if errors.Is(err, store.ErrEventNotFound) {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error verifying ownership", http.StatusInternalServerError)
 		return
@@ -189,10 +203,10 @@ func GetEventRegistrationsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	regs, err := database.GetRegistrationsByEventID(eventID)
+	regs, err := s.Registrations.ListRegistrationsByEvent(r.Context(), eventID)
 	if err != nil {
 		http.Error(w, "Error retrieving registrations", http.StatusInternalServerError)
-		log.Printf("Error: %v", err)
+		slog.ErrorContext(r.Context(), "Error retrieving registrations", "error", err)
 		return
 	}
 
@@ -200,11 +214,10 @@ func GetEventRegistrationsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(regs)
 }
 
-func CreateEventHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateEventHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(utils.UserIDKey).(int)
-	userRole, _ := r.Context().Value(utils.UserRoleKey).(string)
-	if !ok || userRole != "organiser" {
-		writeJSONError(w, "Only organisers can create events", http.StatusForbidden)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -219,7 +232,7 @@ func CreateEventHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, "Name, date, location required", http.StatusBadRequest)
 		return
 	}
-		eventDate, err := time.Parse("2006-01-02", req.Date)
+	eventDate, err := time.Parse("2006-01-02", req.Date)
 	if err != nil {
 		writeJSONError(w, "Date must be YYYY-MM-DD", http.StatusBadRequest)
 		return
@@ -234,19 +247,20 @@ func CreateEventHandler(w http.ResponseWriter, r *http.Request) {
 		Capacity:    req.Capacity,
 	}
 
-	created, err := database.CreateEvent(e)
+	created, err := s.Events.CreateEvent(r.Context(), e)
 	if err != nil {
-		log.Printf("Error: %v", err)
+		slog.ErrorContext(r.Context(), "Error creating event", "error", err)
 		writeJSONError(w, "Failed to create event", http.StatusInternalServerError)
 		return
 	}
+	metrics.EventsCreated.Inc()
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(created)
 }
 
-func UpdateEventHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateEventHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr, ok := vars["id"]
 	if !ok {
@@ -260,9 +274,8 @@ func UpdateEventHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userID, ok := r.Context().Value(utils.UserIDKey).(int)
-	userRole, _ := r.Context().Value(utils.UserRoleKey).(string)
-	if !ok || userRole != "organiser" {
-		writeJSONError(w, "Only organisers can update events", http.StatusForbidden)
+	if !ok {
+		writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -294,16 +307,14 @@ func UpdateEventHandler(w http.ResponseWriter, r *http.Request) {
 		Capacity:    req.Capacity,
 	}
 
-	updated, err := database.UpdateEvent(e)
+	updated, err := s.Events.UpdateEvent(r.Context(), e)
 	if err != nil {
-		switch err.Error() {
-		case "event not found":
-			writeJSONError(w, "Not found", http.StatusNotFound)
-		case "no permission or event not found":
+		if errors.Is(err, store.ErrEventNotFound) {
 			writeJSONError(w, "Forbidden", http.StatusForbidden)
-		default:
-			writeJSONError(w, "Failed to update", http.StatusInternalServerError)
+			return
 		}
+		slog.ErrorContext(r.Context(), "Error updating event", "event_id", eventID, "error", err)
+		writeJSONError(w, "Failed to update", http.StatusInternalServerError)
 		return
 	}
 
@@ -311,11 +322,10 @@ func UpdateEventHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(updated)
 }
 
-func CancelEventHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CancelEventHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(utils.UserIDKey).(int)
-	userRole, _ := r.Context().Value(utils.UserRoleKey).(string)
-	if !ok || userRole != "organiser" {
-		http.Error(w, "Only organisers can cancel events", http.StatusForbidden)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -332,7 +342,11 @@ func CancelEventHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isOwner, err := database.IsEventOrganizer(eventID, userID)
+	isOwner, err := s.Events.IsEventOrganiser(r.Context(), eventID, userID)
+	if errors.Is(err, store.ErrEventNotFound) {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error verifying ownership", http.StatusInternalServerError)
 		return
@@ -342,11 +356,12 @@ func CancelEventHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := database.CancelEvent(eventID); err != nil {
+	if err := s.Events.CancelEvent(r.Context(), eventID); err != nil {
 		http.Error(w, "Error cancelling event", http.StatusInternalServerError)
-		log.Printf("Error cancelling event: %v", err)
+		slog.ErrorContext(r.Context(), "Error cancelling event", "error", err)
 		return
 	}
+	metrics.EventsCancelled.Inc()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Event cancelled"})
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"event_management/backend/models"
+	"event_management/backend/store"
+	"event_management/backend/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func newTestServer() (*Server, *store.Memory) {
+	mem := store.NewMemory()
+	return NewServer(mem, mem, mem), mem
+}
+
+// serve runs handler for a request made by userID, routed through pattern so
+// that path variables are set.
+func serve(handler http.HandlerFunc, method, pattern, path string, userID int, body string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc(pattern, handler).Methods(method)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), utils.UserIDKey, userID))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRegisterForEvent(t *testing.T) {
+	srv, mem := newTestServer()
+	organiserID := mem.AddUser(models.User{Name: "Olga", Email: "olga@example.com", Verified: true}, "organiser")
+	aliceID := mem.AddUser(models.User{Name: "Alice", Email: "alice@example.com", Verified: true})
+	bobID := mem.AddUser(models.User{Name: "Bob", Email: "bob@example.com", Verified: true})
+	unverifiedID := mem.AddUser(models.User{Name: "Uma", Email: "uma@example.com"})
+
+	event, err := mem.CreateEvent(context.Background(), models.Event{
+		Name: "Meetup", Date: "2027-01-01", Location: "Hall A", Capacity: 1, OrganizerID: organiserID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/events/" + strconv.Itoa(event.ID) + "/register"
+
+	tests := []struct {
+		name   string
+		userID int
+		path   string
+		want   int
+	}{
+		{"unverified email", unverifiedID, path, http.StatusForbidden},
+		{"unknown event", aliceID, "/events/999/register", http.StatusNotFound},
+		{"first registration", aliceID, path, http.StatusCreated},
+		{"already registered", aliceID, path, http.StatusConflict},
+		{"event full", bobID, path, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := serve(srv.RegisterForEventHandler, http.MethodPost, "/events/{id:[0-9]+}/register", tt.path, tt.userID, "")
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+
+	registrations, err := mem.ListRegistrationsByUser(context.Background(), aliceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(registrations) != 1 {
+		t.Fatalf("alice has %d registrations, want 1", len(registrations))
+	}
+	regPath := "/registrations/" + strconv.Itoa(registrations[0].ID)
+
+	rec := serve(srv.CancelRegistrationHandler, http.MethodDelete, "/registrations/{id:[0-9]+}", regPath, bobID, "")
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("cancelling someone else's registration: status = %d, want 403", rec.Code)
+	}
+	rec = serve(srv.CancelRegistrationHandler, http.MethodDelete, "/registrations/{id:[0-9]+}", regPath, aliceID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cancelling own registration: status = %d, want 200", rec.Code)
+	}
+
+	// The freed place can be taken by someone else.
+	rec = serve(srv.RegisterForEventHandler, http.MethodPost, "/events/{id:[0-9]+}/register", path, bobID, "")
+	if rec.Code != http.StatusCreated {
+		t.Errorf("registering after a cancellation: status = %d, want 201", rec.Code)
+	}
+}
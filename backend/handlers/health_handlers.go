@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"event_management/backend/database"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var shuttingDown atomic.Bool
+
+// MarkShuttingDown makes /readyz fail so the orchestrator stops routing new
+// traffic while in-flight requests drain.
+func MarkShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// HealthzHandler only reports that the process is serving requests.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// ReadyzHandler reports whether this instance should receive traffic: the
+// database answers and its schema matches this build.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	if shuttingDown.Load() {
+		writeJSONError(w, "Shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := database.Ping(ctx); err != nil {
+		slog.WarnContext(r.Context(), "Readiness check failed", "error", err)
+		writeJSONError(w, "Database unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if err := database.CheckSchema(); err != nil {
+		slog.WarnContext(r.Context(), "Readiness check failed", "error", err)
+		writeJSONError(w, "Database schema is not current", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+}
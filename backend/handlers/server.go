@@ -0,0 +1,14 @@
+package handlers
+
+import "event_management/backend/store"
+
+// Server holds the stores the handlers read and write through.
+type Server struct {
+	Events        store.EventStore
+	Registrations store.RegistrationStore
+	Users         store.UserStore
+}
+
+func NewServer(events store.EventStore, registrations store.RegistrationStore, users store.UserStore) *Server {
+	return &Server{Events: events, Registrations: registrations, Users: users}
+}
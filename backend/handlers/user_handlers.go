@@ -2,11 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
-	"strings"
 
-	"event_management/backend/database"
+	"event_management/backend/store"
 	"event_management/backend/utils"
 )
 
@@ -16,17 +16,11 @@ func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
 	json.NewEncoder(w).Encode(map[string]string{"message": message})
 }
 
-func GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
-	userRole, ok := r.Context().Value(utils.UserRoleKey).(string)
-	if !ok || userRole != "admin" {
-		http.Error(w, "Only admins can view all users", http.StatusForbidden)
-		return
-	}
-
-	users, err := database.GetAllUserRoles()
+func (s *Server) GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
+	users, err := s.Users.ListUsersWithRoles(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to retrieve user data", http.StatusInternalServerError)
-		log.Printf("Error retrieving users: %v", err)
+		slog.ErrorContext(r.Context(), "Error retrieving users", "error", err)
 		return
 	}
 
@@ -38,13 +32,7 @@ func GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
-	userRole, ok := r.Context().Value(utils.UserRoleKey).(string)
-	if !ok || userRole != "admin" {
-		http.Error(w, "Only admins can deactivate users", http.StatusForbidden)
-		return
-	}
-
+func (s *Server) DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
 	var requestData struct {
 		Email string `json:"email"`
 		Role  string `json:"role"`
@@ -60,13 +48,15 @@ func DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := database.DeactivateUser(requestData.Email, requestData.Role)
+	err := s.Users.DeactivateUser(r.Context(), requestData.Email, requestData.Role)
 	if err != nil {
-		if strings.Contains(err.Error(), "no user found") {
+		if errors.Is(err, store.ErrUserNotFound) || errors.Is(err, store.ErrRoleNotAssigned) {
 			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if errors.Is(err, store.ErrInvalidRole) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 		} else {
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			log.Printf("Error deactivating user: %v", err)
+			slog.ErrorContext(r.Context(), "Error deactivating user", "error", err)
 		}
 		return
 	}
@@ -80,17 +70,93 @@ func DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func GetUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GrantUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if requestData.Email == "" || requestData.Role == "" {
+		http.Error(w, "Email and role are required", http.StatusBadRequest)
+		return
+	}
+
+	err := s.Users.GrantRole(r.Context(), requestData.Email, requestData.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, store.ErrInvalidRole):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Error granting role", "error", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Role granted successfully",
+		"email":   requestData.Email,
+		"role":    requestData.Role,
+	})
+}
+
+func (s *Server) RevokeUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if requestData.Email == "" || requestData.Role == "" {
+		http.Error(w, "Email and role are required", http.StatusBadRequest)
+		return
+	}
+
+	err := s.Users.RevokeRole(r.Context(), requestData.Email, requestData.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrLastRole):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, store.ErrUserNotFound), errors.Is(err, store.ErrRoleNotAssigned):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Error revoking role", "error", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Role revoked successfully",
+		"email":   requestData.Email,
+		"role":    requestData.Role,
+	})
+}
+
+func (s *Server) GetUserProfileHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(utils.UserIDKey).(int)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	user, err := database.GetUserByID(userID)
+	user, err := s.Users.GetUser(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Error retrieving user profile", http.StatusInternalServerError)
-		log.Printf("Error retrieving user profile: %v", err)
+		slog.ErrorContext(r.Context(), "Error retrieving user profile", "error", err)
 		return
 	}
 
@@ -104,7 +170,7 @@ func GetUserProfileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func UpdateUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateUserProfileHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(utils.UserIDKey).(int)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -121,10 +187,10 @@ func UpdateUserProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := database.GetUserByID(userID)
+	user, err := s.Users.GetUser(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Error retrieving user profile", http.StatusInternalServerError)
-		log.Printf("Error retrieving user profile: %v", err)
+		slog.ErrorContext(r.Context(), "Error retrieving user profile", "error", err)
 		return
 	}
 
@@ -133,10 +199,10 @@ func UpdateUserProfileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	user.Phone = profileUpdate.Phone
 
-	updatedUser, err := database.UpdateUser(user)
+	updatedUser, err := s.Users.UpdateProfile(r.Context(), user)
 	if err != nil {
 		http.Error(w, "Error updating user profile", http.StatusInternalServerError)
-		log.Printf("Error updating user profile: %v", err)
+		slog.ErrorContext(r.Context(), "Error updating user profile", "error", err)
 		return
 	}
 
@@ -150,22 +216,19 @@ func UpdateUserProfileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func GetUserRegistrationsHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("GetUserRegistrationsHandler called")
+func (s *Server) GetUserRegistrationsHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(utils.UserIDKey).(int)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	log.Printf("Fetching registrations for userID: %d", userID)
 
-	registrations, err := database.GetRegistrationsByUserID(userID)
+	registrations, err := s.Registrations.ListRegistrationsByUser(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Error retrieving registrations", http.StatusInternalServerError)
-		log.Printf("Error retrieving registrations: %v", err)
+		slog.ErrorContext(r.Context(), "Error retrieving registrations", "error", err)
 		return
 	}
-	log.Printf("Found %d registrations for userID: %d", len(registrations), userID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"event_management/backend/models"
+)
+
+func TestUpdateUserProfile(t *testing.T) {
+	srv, mem := newTestServer()
+	userID := mem.AddUser(models.User{Name: "Alice", Email: "alice@example.com", Phone: "123", Password: "hash"})
+
+	rec := serve(srv.UpdateUserProfileHandler, http.MethodPut, "/user/profile", "/user/profile", userID, `{"name": "Alice B", "phone": "456"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", rec.Code, rec.Body)
+	}
+
+	var user models.User
+	if err := json.NewDecoder(rec.Body).Decode(&user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "Alice B" || user.Phone != "456" || user.Email != "alice@example.com" {
+		t.Errorf("updated profile = %+v", user)
+	}
+	if user.Password != "" {
+		t.Error("profile response contains the password hash")
+	}
+}
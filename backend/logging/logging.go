@@ -0,0 +1,64 @@
+// Package logging configures the structured logger and the HTTP middleware
+// that ties log lines to the request that produced them.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Setup installs a JSON (or text) slog handler as the default logger. Lines
+// logged with a request's context get its request ID, user ID and the ID of
+// an impersonating admin, and the standard log package writes through the
+// same handler.
+func Setup(w io.Writer, level, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format %q", format)
+	}
+
+	slog.SetDefault(slog.New(contextHandler{handler}))
+	// What is still written with the log package are fatal startup errors.
+	slog.SetLogLoggerLevel(slog.LevelError)
+	return nil
+}
+
+// contextHandler adds the request attributes stored by Middleware.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if info := requestInfoFrom(ctx); info != nil {
+		r.AddAttrs(slog.String("request_id", info.id))
+		if info.userID != 0 {
+			r.AddAttrs(slog.Int("user_id", info.userID))
+		}
+		if info.impersonatorID != 0 {
+			r.AddAttrs(slog.Int("impersonator_id", info.impersonatorID))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
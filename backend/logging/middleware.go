@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// incomingRequestID limits which client supplied IDs are trusted, so a
+// caller cannot inject arbitrary text into the logs.
+var incomingRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type contextKey struct{}
+
+// requestInfo is shared by pointer so handlers deeper in the chain can fill
+// in what the access log needs once the request is done.
+type requestInfo struct {
+	id             string
+	route          string
+	userID         int
+	impersonatorID int
+}
+
+func requestInfoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(contextKey{}).(*requestInfo)
+	return info
+}
+
+// RequestID returns the ID of the request ctx belongs to, or "".
+func RequestID(ctx context.Context) string {
+	if info := requestInfoFrom(ctx); info != nil {
+		return info.id
+	}
+	return ""
+}
+
+// Route returns the route template recorded by CaptureRoute, or "unmatched"
+// when no route handled the request.
+func Route(ctx context.Context) string {
+	if info := requestInfoFrom(ctx); info != nil && info.route != "" {
+		return info.route
+	}
+	return "unmatched"
+}
+
+// SetUserID records the authenticated user for the access log and for every
+// later log line of the request.
+func SetUserID(ctx context.Context, userID int) {
+	if info := requestInfoFrom(ctx); info != nil {
+		info.userID = userID
+	}
+}
+
+// SetImpersonatorID records the admin acting as the user, the same way.
+func SetImpersonatorID(ctx context.Context, adminID int) {
+	if info := requestInfoFrom(ctx); info != nil {
+		info.impersonatorID = adminID
+	}
+}
+
+// Middleware takes the request ID from X-Request-ID or generates one, echoes
+// it in the response and writes an access log line when the request is done.
+// It should wrap everything else.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(RequestIDHeader)
+		if !incomingRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		info := &requestInfo{id: id}
+		w.Header().Set(RequestIDHeader, id)
+
+		rec := NewStatusRecorder(w)
+		ctx := context.WithValue(r.Context(), contextKey{}, info)
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		slog.InfoContext(ctx, "request",
+			"method", r.Method,
+			"route", Route(ctx),
+			"status", rec.Status(),
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+		)
+	})
+}
+
+// CaptureRoute records the matched route template, e.g. /events/{id}, which
+// keeps the access log free of IDs. Register it with Router.Use.
+func CaptureRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info := requestInfoFrom(r.Context()); info != nil {
+			if route := mux.CurrentRoute(r); route != nil {
+				info.route, _ = route.GetPathTemplate()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// OutboxSender writes every message to a file instead of delivering it, which
+// lets the mail flows be exercised without an SMTP server.
+type OutboxSender struct {
+	Dir  string
+	From string
+}
+
+func NewOutboxSender(dir, from string) (*OutboxSender, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &OutboxSender{Dir: dir, From: from}, nil
+}
+
+func (s *OutboxSender) Send(msg Message) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	return os.WriteFile(filepath.Join(s.Dir, name), format(s.From, msg), 0o600)
+}
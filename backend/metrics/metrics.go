@@ -0,0 +1,116 @@
+// Package metrics exposes Prometheus metrics for HTTP traffic, the database
+// pool and the business events the API handles.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"event_management/backend/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "event_management"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by route template, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	RegistrationsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_created_total",
+		Help:      "Event registrations created.",
+	})
+	RegistrationsCancelled = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_cancelled_total",
+		Help:      "Event registrations cancelled by their owner.",
+	})
+	EventFullRejections = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_rejected_event_full_total",
+		Help:      "Registrations rejected because the event was at capacity.",
+	})
+	EventsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_created_total",
+		Help:      "Events created by organisers.",
+	})
+	EventsCancelled = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_cancelled_total",
+		Help:      "Events cancelled by organisers.",
+	})
+
+	// FailedLogins is labelled by the step that failed: "password" or
+	// "second_factor".
+	FailedLogins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Failed login attempts by step.",
+	}, []string{"step"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		RegistrationsCreated,
+		RegistrationsCancelled,
+		EventFullRejections,
+		EventsCreated,
+		EventsCancelled,
+		FailedLogins,
+	)
+	FailedLogins.WithLabelValues("password")
+	FailedLogins.WithLabelValues("second_factor")
+}
+
+// RegisterDB exports the sql.DBStats of the pool: open, in-use and idle
+// connections, and how often and how long callers waited for one.
+func RegisterDB(db *sql.DB, name string) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// Middleware times every request. It must run inside logging.Middleware,
+// which provides the route template, so paths with IDs share one series.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := logging.NewStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		httpRequestDuration.WithLabelValues(
+			logging.Route(r.Context()),
+			method(r.Method),
+			strconv.Itoa(rec.Status()),
+		).Observe(time.Since(start).Seconds())
+	})
+}
+
+// method keeps the label set bounded when clients send arbitrary methods.
+func method(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return m
+	}
+	return "OTHER"
+}
@@ -0,0 +1,26 @@
+package models
+
+type AccountProfile struct {
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	Email     string   `json:"email"`
+	Phone     string   `json:"phone"`
+	Roles     []string `json:"roles"`
+	Verified  bool     `json:"verified"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+type AccountRegistration struct {
+	Registration
+	EventName     string `json:"eventName"`
+	EventDate     string `json:"eventDate"`
+	EventLocation string `json:"eventLocation"`
+	Cancelled     bool   `json:"cancelled"`
+}
+
+type AccountExport struct {
+	ExportedAt      string                `json:"exportedAt"`
+	Profile         AccountProfile        `json:"profile"`
+	Registrations   []AccountRegistration `json:"registrations"`
+	OrganisedEvents []Event               `json:"organisedEvents"`
+}
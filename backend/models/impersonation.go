@@ -0,0 +1,22 @@
+package models
+
+type Impersonation struct {
+	ID         int    `json:"id"`
+	AdminID    int    `json:"adminId"`
+	AdminEmail string `json:"adminEmail"`
+	UserID     int    `json:"userId"`
+	UserEmail  string `json:"userEmail"`
+	Reason     string `json:"reason"`
+	IP         string `json:"ip"`
+	StartedAt  string `json:"startedAt"`
+	ExpiresAt  string `json:"expiresAt"`
+	EndedAt    string `json:"endedAt,omitempty"`
+}
+
+type ImpersonationRequest struct {
+	ID        int    `json:"id"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Status    int    `json:"status"`
+	CreatedAt string `json:"createdAt"`
+}
@@ -0,0 +1,23 @@
+package models
+
+type Invitation struct {
+	ID        int    `json:"id"`
+	Role      string `json:"role"`
+	Email     string `json:"email,omitempty"`
+	MaxUses   int    `json:"maxUses"`
+	UseCount  int    `json:"useCount"`
+	ExpiresAt string `json:"expiresAt"`
+	RevokedAt string `json:"revokedAt,omitempty"`
+	CreatedBy int    `json:"createdBy"`
+	CreatedAt string `json:"createdAt"`
+}
+
+type InvitationRedemption struct {
+	ID           int    `json:"id"`
+	InvitationID int    `json:"invitationId"`
+	UserID       int    `json:"userId"`
+	UserEmail    string `json:"userEmail"`
+	IP           string `json:"ip"`
+	UserAgent    string `json:"userAgent"`
+	RedeemedAt   string `json:"redeemedAt"`
+}
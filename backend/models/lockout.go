@@ -0,0 +1,9 @@
+package models
+
+type LoginLockout struct {
+	Email          string `json:"email"`
+	FailedAttempts int    `json:"failedAttempts"`
+	LastFailedAt   string `json:"lastFailedAt"`
+	LockedUntil    string `json:"lockedUntil,omitempty"`
+	Locked         bool   `json:"locked"`
+}
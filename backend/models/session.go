@@ -0,0 +1,18 @@
+package models
+
+type Session struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"userAgent"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"createdAt"`
+	LastSeenAt string `json:"lastSeenAt"`
+	ExpiresAt  string `json:"expiresAt"`
+	Current    bool   `json:"current"`
+}
+
+// SessionInfo describes the device a login comes from.
+type SessionInfo struct {
+	ID        string
+	UserAgent string
+	IP        string
+}
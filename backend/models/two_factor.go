@@ -0,0 +1,12 @@
+package models
+
+type TOTPState struct {
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+}
+
+type RoleTwoFactorPolicy struct {
+	Role     string `json:"role"`
+	Required bool   `json:"required"`
+}
@@ -1,13 +1,15 @@
 package models
 
 type User struct {
-	ID       int
-	Name     string
-	Email    string
-	Phone    string
-	Password string
-	Role     string 
-	Roles    []Role
+	ID           int
+	Name         string
+	Email        string
+	Phone        string
+	Password     string
+	Role         string
+	Roles        []Role
+	TokenVersion int `json:"-"`
+	Verified     bool
 }
 
 type Role struct {
@@ -22,3 +24,42 @@ type UserRole struct {
 	RoleName   string
 	AssignedAt string
 }
+
+func (u User) RoleNames() []string {
+	names := make([]string, 0, len(u.Roles))
+	for _, r := range u.Roles {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+type Permission struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type APIKey struct {
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expiresAt,omitempty"`
+	LastUsedAt string   `json:"lastUsedAt,omitempty"`
+	RevokedAt  string   `json:"revokedAt,omitempty"`
+	CreatedAt  string   `json:"createdAt"`
+}
+
+// APIKeyOwner is the user an API key acts for, with the key's scopes.
+type APIKeyOwner struct {
+	KeyID  int
+	User   User
+	Scopes []string
+}
+
+type UserWithRoles struct {
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Role  string   `json:"role"`
+	Roles []string `json:"roles"`
+}
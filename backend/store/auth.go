@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"event_management/backend/models"
+	"time"
+)
+
+var (
+	ErrRoleNotFound             = errors.New("role not found")
+	ErrInvalidResetToken        = errors.New("invalid or expired reset token")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrSessionNotFound          = errors.New("session not found")
+	ErrInvalidRefreshToken      = errors.New("invalid refresh token")
+	ErrRefreshTokenReused       = errors.New("refresh token reused")
+	ErrTwoFactorNotEnrolled     = errors.New("two-factor enrolment has not been started")
+	ErrInvalidAPIKey            = errors.New("invalid or expired API key")
+	ErrAPIKeyNotFound           = errors.New("API key not found")
+	ErrUnknownPermission        = errors.New("unknown permission")
+	ErrInvalidInvitation        = errors.New("invalid or expired invitation")
+	ErrInvitationEmailMismatch  = errors.New("invitation is for a different email")
+	ErrInvitationNotFound       = errors.New("invitation not found")
+	ErrImpersonationNotFound    = errors.New("impersonation not found")
+	ErrInvalidLoginState        = errors.New("invalid or expired login state")
+	ErrIdentityInUse            = errors.New("identity is linked to another account")
+)
+
+// CredentialStore covers accounts as the login flows see them: passwords,
+// reset tokens and email verification. Lookups by email only find active
+// users and return ErrUserNotFound otherwise.
+type CredentialStore interface {
+	// AuthenticateUser returns the user with their password hash.
+	AuthenticateUser(ctx context.Context, email string) (*models.User, error)
+	CreateUser(ctx context.Context, user models.User, hashedPassword []byte) (int, error)
+	GetUserIDByEmail(ctx context.Context, email string) (int, error)
+	UpdatePasswordHash(ctx context.Context, userID int, hashedPassword []byte) error
+	// ChangePassword stores a new password, ends every existing session and
+	// revokes the user's API keys.
+	ChangePassword(ctx context.Context, userID int, hashedPassword []byte) error
+	// CreatePasswordResetToken invalidates the user's earlier reset tokens.
+	CreatePasswordResetToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error
+	GetPasswordResetEmail(ctx context.Context, tokenHash string) (string, error)
+	// ResetPassword uses up the token, sets the password, ends every session
+	// of the user and revokes their API keys.
+	ResetPassword(ctx context.Context, tokenHash string, hashedPassword []byte) (int, error)
+	// CreateEmailVerificationToken invalidates the user's earlier tokens.
+	CreateEmailVerificationToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error
+	VerifyEmail(ctx context.Context, tokenHash string) (int, error)
+	GetUnverifiedUserIDByEmail(ctx context.Context, email string) (int, error)
+	// CountVerificationEmailsSince reports how many verification emails were
+	// sent to a user after each of the two cut-off times.
+	CountVerificationEmailsSince(ctx context.Context, userID int, recent, window time.Time) (int, int, error)
+}
+
+// LockoutStore counts failed logins per email.
+type LockoutStore interface {
+	// GetLockoutRemaining returns how long the account is still locked, or
+	// zero.
+	GetLockoutRemaining(ctx context.Context, email string) (time.Duration, error)
+	// RecordFailedLogin counts a failed attempt and returns the running
+	// total. Failures older than resetAfter no longer count.
+	RecordFailedLogin(ctx context.Context, email string, resetAfter time.Duration) (int, error)
+	LockAccount(ctx context.Context, email string, until time.Time) error
+	ClearLockout(ctx context.Context, email string) (bool, error)
+	GetLoginLockouts(ctx context.Context) ([]models.LoginLockout, error)
+}
+
+// SessionStore keeps login sessions, their rotating refresh tokens and the
+// list of revoked access tokens.
+type SessionStore interface {
+	// CreateRefreshToken starts a session and stores its first refresh token.
+	CreateRefreshToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time, session models.SessionInfo) error
+	// RotateRefreshToken swaps a refresh token for a new one in the same
+	// session and returns the owning user and session ID. A token that was
+	// already rotated revokes every session of the user and returns
+	// ErrRefreshTokenReused.
+	RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time, session models.SessionInfo) (int, string, error)
+	// RevokeRefreshToken logs out the session the refresh token belongs to.
+	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+	RevokeAccessToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error
+	// IsAccessTokenActive checks a token against the user's token version,
+	// the revoked token list and, when sessionID is set, its session.
+	IsAccessTokenActive(ctx context.Context, userID, tokenVersion int, jti, sessionID string) (bool, error)
+	TouchSession(ctx context.Context, sessionID string) error
+	// GetUserSessions lists the live sessions, most recently used first.
+	GetUserSessions(ctx context.Context, userID int) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID int, sessionID string) error
+	// RevokeAllUserTokens ends every session of a user and invalidates their
+	// outstanding access tokens.
+	RevokeAllUserTokens(ctx context.Context, userID int) error
+}
+
+type TwoFactorStore interface {
+	// GetTwoFactorStatus reports whether the user has finished enrolment and
+	// whether one of their roles requires it.
+	GetTwoFactorStatus(ctx context.Context, userID int) (bool, bool, error)
+	// GetTOTPState returns ErrTwoFactorNotEnrolled before enrolment starts.
+	GetTOTPState(ctx context.Context, userID int) (*models.TOTPState, error)
+	// SavePendingTOTPSecret starts or restarts an enrolment; the secret of a
+	// finished enrolment is kept.
+	SavePendingTOTPSecret(ctx context.Context, userID int, secret string) error
+	// MarkTOTPStepUsed returns false when the step or a later one was already
+	// used.
+	MarkTOTPStepUsed(ctx context.Context, userID int, step int64) (bool, error)
+	EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes []string) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int, recoveryCodeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+	DisableTOTP(ctx context.Context, userID int) error
+	GetRoleTwoFactorPolicies(ctx context.Context) ([]models.RoleTwoFactorPolicy, error)
+	SetRoleTwoFactorRequired(ctx context.Context, role string, required bool) error
+}
+
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, userID int, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (int, error)
+	GetAPIKeys(ctx context.Context, userID int) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID int) error
+	// RevokeUserAPIKeys revokes every key of a user. RevokeAllUserTokens does
+	// not, so that a role change does not break the user's integrations.
+	RevokeUserAPIKeys(ctx context.Context, userID int) error
+	// AuthenticateAPIKey finds an active key of an active user by its hash,
+	// or returns ErrInvalidAPIKey.
+	AuthenticateAPIKey(ctx context.Context, keyHash string) (*models.APIKeyOwner, error)
+}
+
+type PermissionStore interface {
+	GetAllPermissions(ctx context.Context) ([]models.Permission, error)
+	// GetRolePermissionMap returns the permission names of every role.
+	GetRolePermissionMap(ctx context.Context) (map[string][]string, error)
+	GetRolePermissions(ctx context.Context, role string) ([]string, error)
+	// SetRolePermissions replaces the permissions of a role.
+	SetRolePermissions(ctx context.Context, role string, permissions []string) error
+}
+
+type InvitationStore interface {
+	CreateInvitation(ctx context.Context, role, email string, maxUses int, expiresAt time.Time, createdBy int) (int, error)
+	GetInvitations(ctx context.Context) ([]models.Invitation, error)
+	RevokeInvitation(ctx context.Context, invitationID int) error
+	// RedeemInvitation creates the invited account with the invitation's role
+	// and records the redemption.
+	RedeemInvitation(ctx context.Context, invitationID int, user models.User, hashedPassword []byte, ip, userAgent string) (int, error)
+	GetInvitationRedemptions(ctx context.Context, invitationID int) ([]models.InvitationRedemption, error)
+}
+
+// ImpersonationStore is the audit trail of admins acting as other users.
+type ImpersonationStore interface {
+	CreateImpersonation(ctx context.Context, adminID, userID int, reason, jti, ip string, expiresAt time.Time) (int, error)
+	// IsImpersonationActive reports whether the impersonation has not been
+	// ended and the admin behind it is still active.
+	IsImpersonationActive(ctx context.Context, jti string, adminID int) (bool, error)
+	EndImpersonation(ctx context.Context, jti string) error
+	LogImpersonationRequest(ctx context.Context, jti, method, path string, status int) error
+	GetImpersonations(ctx context.Context) ([]models.Impersonation, error)
+	GetImpersonationRequests(ctx context.Context, impersonationID int) ([]models.ImpersonationRequest, error)
+}
+
+// IdentityStore links accounts to identities at an external provider and
+// keeps the state of logins in progress.
+type IdentityStore interface {
+	SaveOIDCLoginState(ctx context.Context, stateHash, nonce, codeVerifier string, expiresAt time.Time) error
+	// ConsumeOIDCLoginState returns the nonce and PKCE verifier of a login
+	// and deletes them, so a state can only complete one login.
+	ConsumeOIDCLoginState(ctx context.Context, stateHash string) (string, string, error)
+	// GetUserIDByIdentity returns ErrUserNotFound for an identity that is not
+	// linked to an active user.
+	GetUserIDByIdentity(ctx context.Context, issuer, subject string) (int, error)
+	// LinkIdentity records a login through an identity, linking it to the
+	// user if it is not linked yet.
+	LinkIdentity(ctx context.Context, userID int, issuer, subject, email string) error
+	// AddUserIdentity links an identity at the user's request. It returns
+	// ErrIdentityInUse when the identity belongs to someone else.
+	AddUserIdentity(ctx context.Context, userID int, issuer, subject, email string) error
+	CreateExternalUser(ctx context.Context, user models.User, hashedPassword []byte, issuer, subject string) (int, error)
+	// MarkUserVerified marks the account verified if its address is email.
+	MarkUserVerified(ctx context.Context, userID int, email string) error
+	// SyncManagedRoles adds the managed roles in granted and removes the
+	// other managed roles, never the user's last one. It reports whether a
+	// role was removed.
+	SyncManagedRoles(ctx context.Context, userID int, managed, granted []string) (bool, error)
+}
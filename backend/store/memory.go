@@ -0,0 +1,563 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"event_management/backend/models"
+)
+
+var memoryRoles = []models.Role{
+	{ID: 1, Name: "admin"},
+	{ID: 2, Name: "organiser"},
+	{ID: 3, Name: "attendee"},
+}
+
+type memoryEvent struct {
+	event     models.Event
+	cancelled bool
+}
+
+type memoryRegistration struct {
+	reg       models.Registration
+	cancelled bool
+}
+
+type memoryUser struct {
+	user      models.User
+	createdAt string
+	deleted   bool
+}
+
+// Memory implements every store interface with maps guarded by a mutex. It
+// mirrors the SQL store's behaviour closely enough for handler tests.
+type Memory struct {
+	mu            sync.RWMutex
+	users         map[int]*memoryUser
+	events        map[int]*memoryEvent
+	registrations map[int]*memoryRegistration
+	nextID        int
+
+	resetTokens           map[string]*memoryToken
+	verificationTokens    map[string]*memoryToken
+	lockouts              map[string]*memoryLockout
+	sessions              map[string]*memorySession
+	refreshTokens         map[string]*memoryRefreshToken
+	revokedTokens         map[string]time.Time
+	totp                  map[int]*memoryTOTP
+	twoFactorRoles        map[string]bool
+	apiKeys               map[int]*memoryAPIKey
+	rolePermissions       map[string]map[string]bool
+	invitations           map[int]*memoryInvitation
+	redemptions           []models.InvitationRedemption
+	impersonations        map[int]*memoryImpersonation
+	impersonationRequests map[int][]models.ImpersonationRequest
+	loginStates           map[string]*memoryLoginState
+	identities            map[memoryIdentity]int
+}
+
+var (
+	_ EventStore        = (*Memory)(nil)
+	_ RegistrationStore = (*Memory)(nil)
+	_ UserStore         = (*Memory)(nil)
+
+	_ CredentialStore    = (*Memory)(nil)
+	_ LockoutStore       = (*Memory)(nil)
+	_ SessionStore       = (*Memory)(nil)
+	_ TwoFactorStore     = (*Memory)(nil)
+	_ APIKeyStore        = (*Memory)(nil)
+	_ PermissionStore    = (*Memory)(nil)
+	_ InvitationStore    = (*Memory)(nil)
+	_ ImpersonationStore = (*Memory)(nil)
+	_ IdentityStore      = (*Memory)(nil)
+)
+
+func NewMemory() *Memory {
+	m := &Memory{
+		users:         map[int]*memoryUser{},
+		events:        map[int]*memoryEvent{},
+		registrations: map[int]*memoryRegistration{},
+
+		resetTokens:           map[string]*memoryToken{},
+		verificationTokens:    map[string]*memoryToken{},
+		lockouts:              map[string]*memoryLockout{},
+		sessions:              map[string]*memorySession{},
+		refreshTokens:         map[string]*memoryRefreshToken{},
+		revokedTokens:         map[string]time.Time{},
+		totp:                  map[int]*memoryTOTP{},
+		twoFactorRoles:        map[string]bool{},
+		apiKeys:               map[int]*memoryAPIKey{},
+		rolePermissions:       map[string]map[string]bool{},
+		invitations:           map[int]*memoryInvitation{},
+		impersonations:        map[int]*memoryImpersonation{},
+		impersonationRequests: map[int][]models.ImpersonationRequest{},
+		loginStates:           map[string]*memoryLoginState{},
+		identities:            map[memoryIdentity]int{},
+	}
+	for _, role := range memoryRoles {
+		m.rolePermissions[role.Name] = map[string]bool{}
+	}
+	for _, p := range DefaultPermissions {
+		for _, role := range p.Roles {
+			m.rolePermissions[role][p.Name] = true
+		}
+	}
+	return m
+}
+
+func (m *Memory) newID() int {
+	m.nextID++
+	return m.nextID
+}
+
+// AddUser stores a user with the given role names and returns their ID.
+// Unknown role names are ignored; a user without roles becomes an attendee.
+func (m *Memory) AddUser(user models.User, roles ...string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.addUser(user, roles...)
+}
+
+func (m *Memory) addUser(user models.User, roles ...string) int {
+	user.ID = m.newID()
+	user.Roles = nil
+	for _, name := range roles {
+		if role, ok := findRole(name); ok {
+			user.Roles = append(user.Roles, role)
+		}
+	}
+	if len(user.Roles) == 0 {
+		role, _ := findRole("attendee")
+		user.Roles = []models.Role{role}
+	}
+	sortRoles(user.Roles)
+	user.Role = user.Roles[0].Name
+
+	m.users[user.ID] = &memoryUser{user: user, createdAt: now()}
+	return user.ID
+}
+
+func findRole(name string) (models.Role, bool) {
+	for _, role := range memoryRoles {
+		if role.Name == name {
+			return role, true
+		}
+	}
+	return models.Role{}, false
+}
+
+func sortRoles(roles []models.Role) {
+	sort.Slice(roles, func(i, j int) bool { return roles[i].ID < roles[j].ID })
+}
+
+func now() string {
+	return time.Now().Format("2006-01-02 15:04:05")
+}
+
+func (m *Memory) activeUser(userID int) (*memoryUser, bool) {
+	u, ok := m.users[userID]
+	if !ok || u.deleted {
+		return nil, false
+	}
+	return u, true
+}
+
+func (m *Memory) activeUserByEmail(email string) (*memoryUser, error) {
+	for _, u := range m.users {
+		if !u.deleted && strings.EqualFold(u.user.Email, email) {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUserNotFound, email)
+}
+
+func (m *Memory) registeredCount(eventID int) int {
+	count := 0
+	for _, r := range m.registrations {
+		if r.reg.EventID == eventID && !r.cancelled {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *Memory) ListEvents(ctx context.Context) ([]models.EventWithRegistrationCount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := []models.EventWithRegistrationCount{}
+	for _, e := range m.events {
+		if !e.cancelled {
+			events = append(events, models.EventWithRegistrationCount{Event: e.event, RegisteredCount: m.registeredCount(e.event.ID)})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Date < events[j].Date })
+	return events, nil
+}
+
+func (m *Memory) ListEventsByOrganiser(ctx context.Context, organiserID int) ([]models.EventWithRegistrationCount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := []models.EventWithRegistrationCount{}
+	for _, e := range m.events {
+		if !e.cancelled && e.event.OrganizerID == organiserID {
+			events = append(events, models.EventWithRegistrationCount{Event: e.event, RegisteredCount: m.registeredCount(e.event.ID)})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Date > events[j].Date })
+	return events, nil
+}
+
+func (m *Memory) GetEvent(ctx context.Context, eventID int) (models.Event, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.events[eventID]
+	if !ok || e.cancelled {
+		return models.Event{}, ErrEventNotFound
+	}
+	return e.event, nil
+}
+
+func (m *Memory) CreateEvent(ctx context.Context, event models.Event) (models.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if event.Status == "" {
+		event.Status = "active"
+	}
+	event.ID = m.newID()
+	m.events[event.ID] = &memoryEvent{event: event, cancelled: event.Status == "cancelled"}
+	return event, nil
+}
+
+func (m *Memory) UpdateEvent(ctx context.Context, event models.Event) (models.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.events[event.ID]
+	if !ok || e.event.OrganizerID != event.OrganizerID {
+		return event, ErrEventNotFound
+	}
+	e.event.Name = event.Name
+	e.event.Description = event.Description
+	e.event.Date = event.Date
+	e.event.Location = event.Location
+	e.event.Capacity = event.Capacity
+	return event, nil
+}
+
+func (m *Memory) CancelEvent(ctx context.Context, eventID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.events[eventID]; ok {
+		e.cancelled = true
+		e.event.Status = "cancelled"
+	}
+	return nil
+}
+
+func (m *Memory) IsEventOrganiser(ctx context.Context, eventID, userID int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.events[eventID]
+	if !ok {
+		return false, ErrEventNotFound
+	}
+	return e.event.OrganizerID == userID, nil
+}
+
+func (m *Memory) ListRegistrationsByEvent(ctx context.Context, eventID int) ([]models.RegistrationWithUserDetails, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	regs := []models.RegistrationWithUserDetails{}
+	for _, r := range m.registrations {
+		if r.reg.EventID != eventID || r.cancelled {
+			continue
+		}
+		details := models.RegistrationWithUserDetails{Registration: r.reg}
+		if u, ok := m.users[r.reg.UserID]; ok {
+			details.UserName = u.user.Name
+			details.Email = u.user.Email
+		}
+		regs = append(regs, details)
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i].RegistrationDate > regs[j].RegistrationDate })
+	return regs, nil
+}
+
+func (m *Memory) ListRegistrationsByUser(ctx context.Context, userID int) ([]models.Registration, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var regs []models.Registration
+	for _, r := range m.registrations {
+		if r.reg.UserID == userID && !r.cancelled {
+			regs = append(regs, r.reg)
+		}
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i].RegistrationDate > regs[j].RegistrationDate })
+	return regs, nil
+}
+
+func (m *Memory) IsRegistered(ctx context.Context, userID, eventID int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, r := range m.registrations {
+		if r.reg.UserID == userID && r.reg.EventID == eventID && !r.cancelled {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Memory) CreateRegistration(ctx context.Context, reg models.Registration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.events[reg.EventID]
+	if !ok || e.cancelled {
+		return 0, ErrEventNotFound
+	}
+	if m.registeredCount(reg.EventID) >= e.event.Capacity {
+		return 0, ErrEventFull
+	}
+
+	reg.ID = m.newID()
+	m.registrations[reg.ID] = &memoryRegistration{reg: reg}
+	return reg.ID, nil
+}
+
+func (m *Memory) IsRegistrationOwner(ctx context.Context, registrationID, userID int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	r, ok := m.registrations[registrationID]
+	return ok && !r.cancelled && r.reg.UserID == userID, nil
+}
+
+func (m *Memory) CancelRegistration(ctx context.Context, registrationID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, ok := m.registrations[registrationID]; ok {
+		r.cancelled = true
+	}
+	return nil
+}
+
+func (m *Memory) GetUser(ctx context.Context, userID int) (models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.activeUser(userID)
+	if !ok {
+		return models.User{}, ErrUserNotFound
+	}
+	user := u.user
+	user.Roles = append([]models.Role(nil), u.user.Roles...)
+	return user, nil
+}
+
+func (m *Memory) IsUserVerified(ctx context.Context, userID int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.users[userID]
+	if !ok {
+		return false, ErrUserNotFound
+	}
+	return u.user.Verified, nil
+}
+
+func (m *Memory) UpdateProfile(ctx context.Context, user models.User) (models.User, error) {
+	m.mu.Lock()
+	u, ok := m.activeUser(user.ID)
+	if ok {
+		u.user.Name = user.Name
+		u.user.Phone = user.Phone
+	}
+	m.mu.Unlock()
+
+	return m.GetUser(ctx, user.ID)
+}
+
+func (m *Memory) ListUsersWithRoles(ctx context.Context) ([]models.UserWithRoles, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var users []models.UserWithRoles
+	for _, u := range m.users {
+		if u.deleted {
+			continue
+		}
+		users = append(users, models.UserWithRoles{
+			Name:  u.user.Name,
+			Email: u.user.Email,
+			Role:  u.user.Role,
+			Roles: u.user.RoleNames(),
+		})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	return users, nil
+}
+
+func (m *Memory) DeactivateUser(ctx context.Context, email, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, err := m.activeUserByEmail(email)
+	if err != nil {
+		return err
+	}
+	if _, ok := findRole(role); !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidRole, role)
+	}
+	if !hasRole(u.user, role) {
+		return fmt.Errorf("%w: %s is not %s", ErrRoleNotAssigned, email, role)
+	}
+	u.deleted = true
+	m.revokeAllUserTokens(u.user.ID)
+	m.revokeUserAPIKeys(u.user.ID)
+	return nil
+}
+
+func hasRole(user models.User, name string) bool {
+	for _, role := range user.Roles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Memory) GrantRole(ctx context.Context, email, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, err := m.activeUserByEmail(email)
+	if err != nil {
+		return err
+	}
+	r, ok := findRole(role)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidRole, role)
+	}
+	if !hasRole(u.user, role) {
+		u.user.Roles = append(u.user.Roles, r)
+		sortRoles(u.user.Roles)
+		u.user.Role = u.user.Roles[0].Name
+	}
+	return nil
+}
+
+func (m *Memory) RevokeRole(ctx context.Context, email, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, err := m.activeUserByEmail(email)
+	if err != nil {
+		return err
+	}
+	if !hasRole(u.user, role) {
+		return fmt.Errorf("%w: %s is not %s", ErrRoleNotAssigned, email, role)
+	}
+	if len(u.user.Roles) <= 1 {
+		return ErrLastRole
+	}
+
+	roles := u.user.Roles[:0]
+	for _, r := range u.user.Roles {
+		if r.Name != role {
+			roles = append(roles, r)
+		}
+	}
+	u.user.Roles = roles
+	u.user.Role = roles[0].Name
+	m.revokeAllUserTokens(u.user.ID)
+	return nil
+}
+
+func (m *Memory) ExportAccount(ctx context.Context, userID int) (*models.AccountExport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.activeUser(userID)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	export := models.AccountExport{
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Profile: models.AccountProfile{
+			ID:        u.user.ID,
+			Name:      u.user.Name,
+			Email:     u.user.Email,
+			Phone:     u.user.Phone,
+			Roles:     u.user.RoleNames(),
+			Verified:  u.user.Verified,
+			CreatedAt: u.createdAt,
+		},
+		Registrations:   []models.AccountRegistration{},
+		OrganisedEvents: []models.Event{},
+	}
+
+	for _, r := range m.registrations {
+		if r.reg.UserID != userID {
+			continue
+		}
+		reg := models.AccountRegistration{Registration: r.reg, Cancelled: r.cancelled}
+		if e, ok := m.events[r.reg.EventID]; ok {
+			reg.EventName = e.event.Name
+			reg.EventDate = e.event.Date
+			reg.EventLocation = e.event.Location
+		}
+		export.Registrations = append(export.Registrations, reg)
+	}
+	for _, e := range m.events {
+		if e.event.OrganizerID == userID {
+			export.OrganisedEvents = append(export.OrganisedEvents, e.event)
+		}
+	}
+	return &export, nil
+}
+
+func (m *Memory) DeleteAccount(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.activeUser(userID)
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	today := time.Now().Format("2006-01-02")
+	for _, e := range m.events {
+		if e.event.OrganizerID == userID && !e.cancelled && e.event.Date > today {
+			return ErrUpcomingEvents
+		}
+	}
+	for _, r := range m.registrations {
+		if e, ok := m.events[r.reg.EventID]; ok && r.reg.UserID == userID && e.event.Date > today {
+			r.cancelled = true
+		}
+	}
+
+	u.deleted = true
+	u.user.Name = "Deleted user"
+	u.user.Email = fmt.Sprintf("deleted-%d@deleted.invalid", userID)
+	u.user.Phone = ""
+	u.user.Password = ""
+	u.user.Verified = false
+	return nil
+}
@@ -0,0 +1,1109 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"event_management/backend/models"
+)
+
+type memoryToken struct {
+	userID    int
+	expiresAt time.Time
+	createdAt time.Time
+	used      bool
+}
+
+type memoryLockout struct {
+	failedAttempts int
+	lastFailedAt   time.Time
+	lockedUntil    time.Time
+}
+
+type memorySession struct {
+	userID     int
+	info       models.SessionInfo
+	createdAt  time.Time
+	lastSeenAt time.Time
+	expiresAt  time.Time
+	revoked    bool
+}
+
+type memoryRefreshToken struct {
+	userID    int
+	sessionID string
+	expiresAt time.Time
+	revoked   bool
+	replaced  bool
+}
+
+type memoryTOTP struct {
+	state         models.TOTPState
+	recoveryCodes map[string]bool
+}
+
+type memoryAPIKey struct {
+	key       models.APIKey
+	userID    int
+	hash      string
+	expiresAt *time.Time
+	revoked   bool
+}
+
+type memoryInvitation struct {
+	inv       models.Invitation
+	expiresAt time.Time
+}
+
+type memoryImpersonation struct {
+	imp models.Impersonation
+	jti string
+}
+
+type memoryLoginState struct {
+	nonce        string
+	codeVerifier string
+	expiresAt    time.Time
+}
+
+type memoryIdentity struct {
+	issuer  string
+	subject string
+}
+
+func formatTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func (m *Memory) AuthenticateUser(ctx context.Context, email string) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, err := m.activeUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	user := u.user
+	user.Roles = append([]models.Role(nil), u.user.Roles...)
+	return &user, nil
+}
+
+func (m *Memory) CreateUser(ctx context.Context, user models.User, hashedPassword []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.activeUserByEmail(user.Email); err == nil {
+		return 0, fmt.Errorf("email %s is already registered", user.Email)
+	}
+	user.Password = string(hashedPassword)
+	return m.addUser(user, user.Role), nil
+}
+
+func (m *Memory) GetUserIDByEmail(ctx context.Context, email string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, err := m.activeUserByEmail(email)
+	if err != nil {
+		return 0, err
+	}
+	return u.user.ID, nil
+}
+
+func (m *Memory) UpdatePasswordHash(ctx context.Context, userID int, hashedPassword []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, ok := m.activeUser(userID); ok {
+		u.user.Password = string(hashedPassword)
+	}
+	return nil
+}
+
+func (m *Memory) ChangePassword(ctx context.Context, userID int, hashedPassword []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, ok := m.activeUser(userID); ok {
+		u.user.Password = string(hashedPassword)
+	}
+	m.revokeAllUserTokens(userID)
+	m.revokeUserAPIKeys(userID)
+	return nil
+}
+
+// replaceToken marks the user's unused tokens as used and stores a new one.
+func replaceToken(tokens map[string]*memoryToken, userID int, tokenHash string, expiresAt time.Time) {
+	for _, t := range tokens {
+		if t.userID == userID {
+			t.used = true
+		}
+	}
+	tokens[tokenHash] = &memoryToken{userID: userID, expiresAt: expiresAt, createdAt: time.Now()}
+}
+
+func (m *Memory) CreatePasswordResetToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	replaceToken(m.resetTokens, userID, tokenHash, expiresAt)
+	return nil
+}
+
+// usableResetToken returns a reset token that is unused, unexpired and
+// belongs to an active user.
+func (m *Memory) usableResetToken(tokenHash string) (*memoryToken, *memoryUser, bool) {
+	t, ok := m.resetTokens[tokenHash]
+	if !ok || t.used || !t.expiresAt.After(time.Now()) {
+		return nil, nil, false
+	}
+	u, ok := m.activeUser(t.userID)
+	return t, u, ok
+}
+
+func (m *Memory) GetPasswordResetEmail(ctx context.Context, tokenHash string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, u, ok := m.usableResetToken(tokenHash)
+	if !ok {
+		return "", ErrInvalidResetToken
+	}
+	return u.user.Email, nil
+}
+
+func (m *Memory) ResetPassword(ctx context.Context, tokenHash string, hashedPassword []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, u, ok := m.usableResetToken(tokenHash)
+	if !ok {
+		return 0, ErrInvalidResetToken
+	}
+	t.used = true
+	u.user.Password = string(hashedPassword)
+	m.revokeAllUserTokens(t.userID)
+	m.revokeUserAPIKeys(t.userID)
+	return t.userID, nil
+}
+
+func (m *Memory) CreateEmailVerificationToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	replaceToken(m.verificationTokens, userID, tokenHash, expiresAt)
+	return nil
+}
+
+func (m *Memory) VerifyEmail(ctx context.Context, tokenHash string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.verificationTokens[tokenHash]
+	if !ok || t.used || !t.expiresAt.After(time.Now()) {
+		return 0, ErrInvalidVerificationToken
+	}
+	t.used = true
+	if u, ok := m.users[t.userID]; ok {
+		u.user.Verified = true
+	}
+	return t.userID, nil
+}
+
+func (m *Memory) GetUnverifiedUserIDByEmail(ctx context.Context, email string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, err := m.activeUserByEmail(email)
+	if err != nil {
+		return 0, err
+	}
+	if u.user.Verified {
+		return 0, ErrUserNotFound
+	}
+	return u.user.ID, nil
+}
+
+func (m *Memory) CountVerificationEmailsSince(ctx context.Context, userID int, recent, window time.Time) (int, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var recentCount, windowCount int
+	for _, t := range m.verificationTokens {
+		if t.userID != userID || !t.createdAt.After(window) {
+			continue
+		}
+		windowCount++
+		if t.createdAt.After(recent) {
+			recentCount++
+		}
+	}
+	return recentCount, windowCount, nil
+}
+
+func (m *Memory) GetLockoutRemaining(ctx context.Context, email string) (time.Duration, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	l, ok := m.lockouts[email]
+	if !ok {
+		return 0, nil
+	}
+	remaining := time.Until(l.lockedUntil)
+	if remaining <= 0 {
+		return 0, nil
+	}
+	return remaining.Truncate(time.Second) + time.Second, nil
+}
+
+func (m *Memory) RecordFailedLogin(ctx context.Context, email string, resetAfter time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	l, ok := m.lockouts[email]
+	if !ok {
+		l = &memoryLockout{}
+		m.lockouts[email] = l
+	}
+	if l.lastFailedAt.Before(now.Add(-resetAfter)) {
+		l.failedAttempts = 0
+	}
+	l.failedAttempts++
+	l.lastFailedAt = now
+	return l.failedAttempts, nil
+}
+
+func (m *Memory) LockAccount(ctx context.Context, email string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if l, ok := m.lockouts[email]; ok {
+		l.lockedUntil = until
+	}
+	return nil
+}
+
+func (m *Memory) ClearLockout(ctx context.Context, email string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.lockouts[email]
+	delete(m.lockouts, email)
+	return ok, nil
+}
+
+func (m *Memory) GetLoginLockouts(ctx context.Context) ([]models.LoginLockout, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	lockouts := []models.LoginLockout{}
+	for email, l := range m.lockouts {
+		lockout := models.LoginLockout{
+			Email:          email,
+			FailedAttempts: l.failedAttempts,
+			LastFailedAt:   formatTime(l.lastFailedAt),
+			Locked:         l.lockedUntil.After(now),
+		}
+		if !l.lockedUntil.IsZero() {
+			lockout.LockedUntil = formatTime(l.lockedUntil)
+		}
+		lockouts = append(lockouts, lockout)
+	}
+	sort.Slice(lockouts, func(i, j int) bool { return lockouts[i].LastFailedAt > lockouts[j].LastFailedAt })
+	return lockouts, nil
+}
+
+func (m *Memory) createSession(userID int, info models.SessionInfo, expiresAt time.Time) {
+	now := time.Now()
+	m.sessions[info.ID] = &memorySession{
+		userID:     userID,
+		info:       info,
+		createdAt:  now,
+		lastSeenAt: now,
+		expiresAt:  expiresAt,
+	}
+}
+
+func (m *Memory) CreateRefreshToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time, session models.SessionInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.createSession(userID, session, expiresAt)
+	m.refreshTokens[tokenHash] = &memoryRefreshToken{userID: userID, sessionID: session.ID, expiresAt: expiresAt}
+	return nil
+}
+
+func (m *Memory) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time, session models.SessionInfo) (int, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.refreshTokens[oldHash]
+	if !ok {
+		return 0, "", ErrInvalidRefreshToken
+	}
+	if t.revoked && t.replaced {
+		m.revokeAllUserTokens(t.userID)
+		return 0, "", ErrRefreshTokenReused
+	}
+	_, alive := m.activeUser(t.userID)
+	s, hasSession := m.sessions[t.sessionID]
+	if t.revoked || !t.expiresAt.After(time.Now()) || !alive || (hasSession && s.revoked) {
+		return 0, "", ErrInvalidRefreshToken
+	}
+
+	if hasSession {
+		s.lastSeenAt = time.Now()
+		s.expiresAt = expiresAt
+	} else {
+		t.sessionID = session.ID
+		m.createSession(t.userID, session, expiresAt)
+	}
+
+	m.refreshTokens[newHash] = &memoryRefreshToken{userID: t.userID, sessionID: t.sessionID, expiresAt: expiresAt}
+	t.revoked = true
+	t.replaced = true
+	return t.userID, t.sessionID, nil
+}
+
+func (m *Memory) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.refreshTokens[tokenHash]
+	if !ok {
+		return nil
+	}
+	m.revokeSession(t.userID, t.sessionID)
+	t.revoked = true
+	return nil
+}
+
+func (m *Memory) RevokeAccessToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for revoked, exp := range m.revokedTokens {
+		if exp.Before(now) {
+			delete(m.revokedTokens, revoked)
+		}
+	}
+	if _, ok := m.revokedTokens[jti]; !ok {
+		m.revokedTokens[jti] = expiresAt
+	}
+	return nil
+}
+
+func (m *Memory) IsAccessTokenActive(ctx context.Context, userID, tokenVersion int, jti, sessionID string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.activeUser(userID)
+	if !ok || u.user.TokenVersion != tokenVersion {
+		return false, nil
+	}
+	if _, revoked := m.revokedTokens[jti]; revoked {
+		return false, nil
+	}
+	if sessionID != "" {
+		s, ok := m.sessions[sessionID]
+		if !ok || s.userID != userID || s.revoked {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (m *Memory) TouchSession(ctx context.Context, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, ok := m.sessions[sessionID]; ok {
+		s.lastSeenAt = time.Now()
+	}
+	return nil
+}
+
+func (m *Memory) GetUserSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	var live []*memorySession
+	for _, s := range m.sessions {
+		if s.userID == userID && !s.revoked && s.expiresAt.After(now) {
+			live = append(live, s)
+		}
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].lastSeenAt.After(live[j].lastSeenAt) })
+
+	sessions := []models.Session{}
+	for _, s := range live {
+		sessions = append(sessions, models.Session{
+			ID:         s.info.ID,
+			UserAgent:  s.info.UserAgent,
+			IP:         s.info.IP,
+			CreatedAt:  formatTime(s.createdAt),
+			LastSeenAt: formatTime(s.lastSeenAt),
+			ExpiresAt:  formatTime(s.expiresAt),
+		})
+	}
+	return sessions, nil
+}
+
+func (m *Memory) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.revokeSession(userID, sessionID) {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (m *Memory) revokeSession(userID int, sessionID string) bool {
+	s, ok := m.sessions[sessionID]
+	if !ok || s.userID != userID || s.revoked {
+		return false
+	}
+	s.revoked = true
+	for _, t := range m.refreshTokens {
+		if t.sessionID == sessionID {
+			t.revoked = true
+		}
+	}
+	return true
+}
+
+func (m *Memory) RevokeAllUserTokens(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokeAllUserTokens(userID)
+	return nil
+}
+
+func (m *Memory) revokeAllUserTokens(userID int) {
+	if u, ok := m.users[userID]; ok {
+		u.user.TokenVersion++
+	}
+	for _, t := range m.refreshTokens {
+		if t.userID == userID {
+			t.revoked = true
+		}
+	}
+	for _, s := range m.sessions {
+		if s.userID == userID {
+			s.revoked = true
+		}
+	}
+}
+
+func (m *Memory) GetTwoFactorStatus(ctx context.Context, userID int) (bool, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.totp[userID]
+	enabled := ok && t.state.Enabled
+
+	required := false
+	if u, ok := m.users[userID]; ok {
+		for _, role := range u.user.Roles {
+			required = required || m.twoFactorRoles[role.Name]
+		}
+	}
+	return enabled, required, nil
+}
+
+func (m *Memory) GetTOTPState(ctx context.Context, userID int) (*models.TOTPState, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.totp[userID]
+	if !ok {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	state := t.state
+	return &state, nil
+}
+
+func (m *Memory) SavePendingTOTPSecret(ctx context.Context, userID int, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.totp[userID]
+	if !ok {
+		m.totp[userID] = &memoryTOTP{state: models.TOTPState{Secret: secret}, recoveryCodes: map[string]bool{}}
+		return nil
+	}
+	if !t.state.Enabled {
+		t.state.Secret = secret
+	}
+	return nil
+}
+
+func (m *Memory) MarkTOTPStepUsed(ctx context.Context, userID int, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.totp[userID]
+	if !ok || t.state.LastUsedStep >= step {
+		return false, nil
+	}
+	t.state.LastUsedStep = step
+	return true, nil
+}
+
+func (m *Memory) EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.totp[userID]
+	if !ok {
+		return nil
+	}
+	t.state.Enabled = true
+	t.replaceRecoveryCodes(recoveryCodeHashes)
+	return nil
+}
+
+func (m *Memory) ReplaceRecoveryCodes(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if t, ok := m.totp[userID]; ok {
+		t.replaceRecoveryCodes(recoveryCodeHashes)
+	}
+	return nil
+}
+
+func (t *memoryTOTP) replaceRecoveryCodes(hashes []string) {
+	t.recoveryCodes = map[string]bool{}
+	for _, hash := range hashes {
+		t.recoveryCodes[hash] = false
+	}
+}
+
+func (m *Memory) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.totp[userID]
+	if !ok {
+		return false, nil
+	}
+	used, exists := t.recoveryCodes[codeHash]
+	if !exists || used {
+		return false, nil
+	}
+	t.recoveryCodes[codeHash] = true
+	return true, nil
+}
+
+func (m *Memory) DisableTOTP(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.totp, userID)
+	return nil
+}
+
+func (m *Memory) GetRoleTwoFactorPolicies(ctx context.Context) ([]models.RoleTwoFactorPolicy, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	policies := []models.RoleTwoFactorPolicy{}
+	for _, role := range memoryRoles {
+		policies = append(policies, models.RoleTwoFactorPolicy{Role: role.Name, Required: m.twoFactorRoles[role.Name]})
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Role < policies[j].Role })
+	return policies, nil
+}
+
+func (m *Memory) SetRoleTwoFactorRequired(ctx context.Context, role string, required bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := findRole(role); !ok {
+		return ErrRoleNotFound
+	}
+	m.twoFactorRoles[role] = required
+	return nil
+}
+
+func (m *Memory) CreateAPIKey(ctx context.Context, userID int, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := &memoryAPIKey{
+		key: models.APIKey{
+			ID:        m.newID(),
+			Name:      name,
+			Prefix:    prefix,
+			Scopes:    append([]string(nil), scopes...),
+			CreatedAt: now(),
+		},
+		userID:    userID,
+		hash:      keyHash,
+		expiresAt: expiresAt,
+	}
+	if expiresAt != nil {
+		key.key.ExpiresAt = formatTime(*expiresAt)
+	}
+	m.apiKeys[key.key.ID] = key
+	return key.key.ID, nil
+}
+
+func (m *Memory) GetAPIKeys(ctx context.Context, userID int) ([]models.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := []models.APIKey{}
+	for _, k := range m.apiKeys {
+		if k.userID == userID {
+			keys = append(keys, k.key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID > keys[j].ID })
+	return keys, nil
+}
+
+func (m *Memory) RevokeAPIKey(ctx context.Context, userID, keyID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k, ok := m.apiKeys[keyID]
+	if !ok || k.userID != userID || k.revoked {
+		return ErrAPIKeyNotFound
+	}
+	m.revokeAPIKey(k)
+	return nil
+}
+
+func (m *Memory) revokeAPIKey(k *memoryAPIKey) {
+	if !k.revoked {
+		k.revoked = true
+		k.key.RevokedAt = now()
+	}
+}
+
+func (m *Memory) RevokeUserAPIKeys(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokeUserAPIKeys(userID)
+	return nil
+}
+
+func (m *Memory) revokeUserAPIKeys(userID int) {
+	for _, k := range m.apiKeys {
+		if k.userID == userID {
+			m.revokeAPIKey(k)
+		}
+	}
+}
+
+func (m *Memory) AuthenticateAPIKey(ctx context.Context, keyHash string) (*models.APIKeyOwner, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range m.apiKeys {
+		if k.hash != keyHash || k.revoked || (k.expiresAt != nil && !k.expiresAt.After(time.Now())) {
+			continue
+		}
+		u, ok := m.activeUser(k.userID)
+		if !ok {
+			break
+		}
+		k.key.LastUsedAt = now()
+
+		owner := models.APIKeyOwner{KeyID: k.key.ID, User: u.user, Scopes: append([]string(nil), k.key.Scopes...)}
+		owner.User.Password = ""
+		owner.User.Roles = append([]models.Role(nil), u.user.Roles...)
+		return &owner, nil
+	}
+	return nil, ErrInvalidAPIKey
+}
+
+func (m *Memory) GetAllPermissions(ctx context.Context) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	for i, p := range DefaultPermissions {
+		permissions = append(permissions, models.Permission{ID: i + 1, Name: p.Name, Description: p.Description})
+	}
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i].Name < permissions[j].Name })
+	return permissions, nil
+}
+
+func (m *Memory) GetRolePermissionMap(ctx context.Context) (map[string][]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rolePermissions := map[string][]string{}
+	for role, granted := range m.rolePermissions {
+		for name := range granted {
+			rolePermissions[role] = append(rolePermissions[role], name)
+		}
+		sort.Strings(rolePermissions[role])
+	}
+	return rolePermissions, nil
+}
+
+func (m *Memory) GetRolePermissions(ctx context.Context, role string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	granted, ok := m.rolePermissions[role]
+	if !ok {
+		return nil, ErrRoleNotFound
+	}
+	permissions := []string{}
+	for name := range granted {
+		permissions = append(permissions, name)
+	}
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+func (m *Memory) SetRolePermissions(ctx context.Context, role string, permissions []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.rolePermissions[role]; !ok {
+		return ErrRoleNotFound
+	}
+	granted := map[string]bool{}
+	for _, name := range permissions {
+		if !isDefaultPermission(name) {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, name)
+		}
+		granted[name] = true
+	}
+	m.rolePermissions[role] = granted
+	return nil
+}
+
+func isDefaultPermission(name string) bool {
+	for _, p := range DefaultPermissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Memory) CreateInvitation(ctx context.Context, role, email string, maxUses int, expiresAt time.Time, createdBy int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := findRole(role); !ok {
+		return 0, ErrRoleNotFound
+	}
+	inv := &memoryInvitation{
+		inv: models.Invitation{
+			ID:        m.newID(),
+			Role:      role,
+			Email:     strings.ToLower(email),
+			MaxUses:   maxUses,
+			ExpiresAt: formatTime(expiresAt),
+			CreatedBy: createdBy,
+			CreatedAt: now(),
+		},
+		expiresAt: expiresAt,
+	}
+	m.invitations[inv.inv.ID] = inv
+	return inv.inv.ID, nil
+}
+
+func (m *Memory) GetInvitations(ctx context.Context) ([]models.Invitation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	invitations := []models.Invitation{}
+	for _, inv := range m.invitations {
+		invitations = append(invitations, inv.inv)
+	}
+	sort.Slice(invitations, func(i, j int) bool { return invitations[i].ID > invitations[j].ID })
+	return invitations, nil
+}
+
+func (m *Memory) RevokeInvitation(ctx context.Context, invitationID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	inv, ok := m.invitations[invitationID]
+	if !ok || inv.inv.RevokedAt != "" {
+		return ErrInvitationNotFound
+	}
+	inv.inv.RevokedAt = now()
+	return nil
+}
+
+func (m *Memory) RedeemInvitation(ctx context.Context, invitationID int, user models.User, hashedPassword []byte, ip, userAgent string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	inv, ok := m.invitations[invitationID]
+	if !ok || inv.inv.RevokedAt != "" || !inv.expiresAt.After(time.Now()) || inv.inv.UseCount >= inv.inv.MaxUses {
+		return 0, ErrInvalidInvitation
+	}
+	if inv.inv.Email != "" && !strings.EqualFold(inv.inv.Email, user.Email) {
+		return 0, ErrInvitationEmailMismatch
+	}
+	if _, err := m.activeUserByEmail(user.Email); err == nil {
+		return 0, fmt.Errorf("email %s is already registered", user.Email)
+	}
+
+	user.Password = string(hashedPassword)
+	user.Verified = inv.inv.Email != ""
+	userID := m.addUser(user, inv.inv.Role)
+
+	inv.inv.UseCount++
+	m.redemptions = append(m.redemptions, models.InvitationRedemption{
+		ID:           m.newID(),
+		InvitationID: invitationID,
+		UserID:       userID,
+		UserEmail:    user.Email,
+		IP:           ip,
+		UserAgent:    userAgent,
+		RedeemedAt:   now(),
+	})
+	return userID, nil
+}
+
+func (m *Memory) GetInvitationRedemptions(ctx context.Context, invitationID int) ([]models.InvitationRedemption, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	redemptions := []models.InvitationRedemption{}
+	for i := len(m.redemptions) - 1; i >= 0; i-- {
+		if m.redemptions[i].InvitationID == invitationID {
+			redemptions = append(redemptions, m.redemptions[i])
+		}
+	}
+	return redemptions, nil
+}
+
+func (m *Memory) CreateImpersonation(ctx context.Context, adminID, userID int, reason, jti, ip string, expiresAt time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	imp := &memoryImpersonation{
+		imp: models.Impersonation{
+			ID:        m.newID(),
+			AdminID:   adminID,
+			UserID:    userID,
+			Reason:    reason,
+			IP:        ip,
+			StartedAt: now(),
+			ExpiresAt: formatTime(expiresAt),
+		},
+		jti: jti,
+	}
+	m.impersonations[imp.imp.ID] = imp
+	return imp.imp.ID, nil
+}
+
+func (m *Memory) impersonationByJTI(jti string) (*memoryImpersonation, bool) {
+	for _, imp := range m.impersonations {
+		if imp.jti == jti {
+			return imp, true
+		}
+	}
+	return nil, false
+}
+
+func (m *Memory) IsImpersonationActive(ctx context.Context, jti string, adminID int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	imp, ok := m.impersonationByJTI(jti)
+	if !ok || imp.imp.AdminID != adminID {
+		return false, nil
+	}
+	_, adminAlive := m.activeUser(adminID)
+	return imp.imp.EndedAt == "" && adminAlive, nil
+}
+
+func (m *Memory) EndImpersonation(ctx context.Context, jti string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	imp, ok := m.impersonationByJTI(jti)
+	if !ok || imp.imp.EndedAt != "" {
+		return ErrImpersonationNotFound
+	}
+	imp.imp.EndedAt = now()
+	return nil
+}
+
+func (m *Memory) LogImpersonationRequest(ctx context.Context, jti, method, path string, status int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	imp, ok := m.impersonationByJTI(jti)
+	if !ok {
+		return nil
+	}
+	m.impersonationRequests[imp.imp.ID] = append(m.impersonationRequests[imp.imp.ID], models.ImpersonationRequest{
+		ID:        m.newID(),
+		Method:    method,
+		Path:      path,
+		Status:    status,
+		CreatedAt: now(),
+	})
+	return nil
+}
+
+func (m *Memory) GetImpersonations(ctx context.Context) ([]models.Impersonation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	impersonations := []models.Impersonation{}
+	for _, i := range m.impersonations {
+		imp := i.imp
+		if u, ok := m.users[imp.AdminID]; ok {
+			imp.AdminEmail = u.user.Email
+		}
+		if u, ok := m.users[imp.UserID]; ok {
+			imp.UserEmail = u.user.Email
+		}
+		impersonations = append(impersonations, imp)
+	}
+	sort.Slice(impersonations, func(i, j int) bool { return impersonations[i].ID > impersonations[j].ID })
+	return impersonations, nil
+}
+
+func (m *Memory) GetImpersonationRequests(ctx context.Context, impersonationID int) ([]models.ImpersonationRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.impersonations[impersonationID]; !ok {
+		return nil, ErrImpersonationNotFound
+	}
+	return append([]models.ImpersonationRequest{}, m.impersonationRequests[impersonationID]...), nil
+}
+
+func (m *Memory) SaveOIDCLoginState(ctx context.Context, stateHash, nonce, codeVerifier string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loginStates[stateHash] = &memoryLoginState{nonce: nonce, codeVerifier: codeVerifier, expiresAt: expiresAt}
+	return nil
+}
+
+func (m *Memory) ConsumeOIDCLoginState(ctx context.Context, stateHash string) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.loginStates[stateHash]
+	delete(m.loginStates, stateHash)
+	if !ok || state.expiresAt.Before(time.Now()) {
+		return "", "", ErrInvalidLoginState
+	}
+	return state.nonce, state.codeVerifier, nil
+}
+
+func (m *Memory) GetUserIDByIdentity(ctx context.Context, issuer, subject string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	userID, ok := m.identities[memoryIdentity{issuer, subject}]
+	if !ok {
+		return 0, ErrUserNotFound
+	}
+	if _, ok := m.activeUser(userID); !ok {
+		return 0, ErrUserNotFound
+	}
+	return userID, nil
+}
+
+func (m *Memory) LinkIdentity(ctx context.Context, userID int, issuer, subject, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := memoryIdentity{issuer, subject}
+	if _, ok := m.identities[id]; !ok {
+		m.identities[id] = userID
+	}
+	return nil
+}
+
+func (m *Memory) AddUserIdentity(ctx context.Context, userID int, issuer, subject, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := memoryIdentity{issuer, subject}
+	if ownerID, ok := m.identities[id]; ok && ownerID != userID {
+		return ErrIdentityInUse
+	}
+	m.identities[id] = userID
+	return nil
+}
+
+func (m *Memory) CreateExternalUser(ctx context.Context, user models.User, hashedPassword []byte, issuer, subject string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.activeUserByEmail(user.Email); err == nil {
+		return 0, fmt.Errorf("email %s is already registered", user.Email)
+	}
+	user.Password = string(hashedPassword)
+	userID := m.addUser(user, user.Role)
+	m.identities[memoryIdentity{issuer, subject}] = userID
+	return userID, nil
+}
+
+func (m *Memory) MarkUserVerified(ctx context.Context, userID int, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, ok := m.users[userID]; ok && strings.EqualFold(u.user.Email, email) {
+		u.user.Verified = true
+	}
+	return nil
+}
+
+func (m *Memory) SyncManagedRoles(ctx context.Context, userID int, managed, granted []string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[userID]
+	if !ok {
+		return false, ErrUserNotFound
+	}
+
+	want := map[string]bool{}
+	for _, role := range granted {
+		want[role] = true
+	}
+
+	for _, name := range managed {
+		if role, ok := findRole(name); ok && want[name] && !hasRole(u.user, name) {
+			u.user.Roles = append(u.user.Roles, role)
+		}
+	}
+
+	removed := false
+	for _, name := range managed {
+		if want[name] || !hasRole(u.user, name) || len(u.user.Roles) <= 1 {
+			continue
+		}
+		roles := u.user.Roles[:0]
+		for _, r := range u.user.Roles {
+			if r.Name != name {
+				roles = append(roles, r)
+			}
+		}
+		u.user.Roles = roles
+		removed = true
+	}
+
+	sortRoles(u.user.Roles)
+	u.user.Role = u.user.Roles[0].Name
+	return removed, nil
+}
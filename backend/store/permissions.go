@@ -0,0 +1,32 @@
+package store
+
+// DefaultPermission is a built-in permission and the roles that get it on a
+// fresh install.
+type DefaultPermission struct {
+	Name        string
+	Description string
+	Roles       []string
+}
+
+var DefaultPermissions = []DefaultPermission{
+	{"event:list", "List upcoming events", []string{"admin", "organiser", "attendee"}},
+	{"event:register", "Register for an event", []string{"attendee"}},
+	{"registration:cancel", "Cancel one's own registration", []string{"attendee"}},
+	{"registration:view_own", "List one's own registrations", []string{"admin", "organiser", "attendee"}},
+	{"profile:view", "View one's own profile", []string{"admin", "organiser", "attendee"}},
+	{"profile:update", "Update one's own profile", []string{"admin", "organiser", "attendee"}},
+	{"event:create", "Create events", []string{"organiser"}},
+	{"event:update", "Update one's own events", []string{"organiser"}},
+	{"event:cancel", "Cancel one's own events", []string{"organiser"}},
+	{"event:view_own", "List one's own events", []string{"organiser"}},
+	{"event:view_registrations", "View registrations for one's own events", []string{"organiser"}},
+	{"user:list", "List all users", []string{"admin"}},
+	{"user:deactivate", "Deactivate users", []string{"admin"}},
+	{"user:manage_roles", "Grant and revoke user roles", []string{"admin"}},
+	{"session:manage", "View and revoke other users' sessions", []string{"admin"}},
+	{"user:impersonate", "Act as another user and view the impersonation audit log", []string{"admin"}},
+	{"invitation:manage", "Issue and revoke invitations", []string{"admin"}},
+	{"lockout:manage", "View and clear login lockouts", []string{"admin"}},
+	{"mfa_policy:manage", "Change per-role two-factor requirements", []string{"admin"}},
+	{"permission:manage", "Edit role permissions", []string{"admin"}},
+}
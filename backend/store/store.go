@@ -0,0 +1,63 @@
+// Package store defines the persistence interfaces the HTTP handlers depend
+// on. The database package provides the SQL implementation and Memory keeps
+// everything in process, for tests and local experiments.
+package store
+
+import (
+	"context"
+	"errors"
+	"event_management/backend/models"
+)
+
+var (
+	ErrUserNotFound         = errors.New("user not found")
+	ErrEventNotFound        = errors.New("event not found")
+	ErrRegistrationNotFound = errors.New("registration not found")
+	ErrEventFull            = errors.New("event is full")
+	ErrInvalidRole          = errors.New("invalid role")
+	ErrRoleNotAssigned      = errors.New("user does not have this role")
+	ErrLastRole             = errors.New("cannot remove the user's only role")
+	ErrUpcomingEvents       = errors.New("user still organises upcoming events")
+)
+
+// EventStore only returns events that have not been cancelled, except
+// where noted.
+type EventStore interface {
+	ListEvents(ctx context.Context) ([]models.EventWithRegistrationCount, error)
+	ListEventsByOrganiser(ctx context.Context, organiserID int) ([]models.EventWithRegistrationCount, error)
+	GetEvent(ctx context.Context, eventID int) (models.Event, error)
+	CreateEvent(ctx context.Context, event models.Event) (models.Event, error)
+	// UpdateEvent returns ErrEventNotFound unless event.OrganizerID owns the
+	// event.
+	UpdateEvent(ctx context.Context, event models.Event) (models.Event, error)
+	CancelEvent(ctx context.Context, eventID int) error
+	// IsEventOrganiser also considers cancelled events.
+	IsEventOrganiser(ctx context.Context, eventID, userID int) (bool, error)
+}
+
+type RegistrationStore interface {
+	ListRegistrationsByEvent(ctx context.Context, eventID int) ([]models.RegistrationWithUserDetails, error)
+	ListRegistrationsByUser(ctx context.Context, userID int) ([]models.Registration, error)
+	IsRegistered(ctx context.Context, userID, eventID int) (bool, error)
+	// CreateRegistration enforces the event's capacity and returns the new
+	// registration ID.
+	CreateRegistration(ctx context.Context, reg models.Registration) (int, error)
+	IsRegistrationOwner(ctx context.Context, registrationID, userID int) (bool, error)
+	CancelRegistration(ctx context.Context, registrationID int) error
+}
+
+// UserStore covers active users. Changing a user's roles or deactivating
+// them also ends their sessions.
+type UserStore interface {
+	GetUser(ctx context.Context, userID int) (models.User, error)
+	IsUserVerified(ctx context.Context, userID int) (bool, error)
+	// UpdateProfile saves the name and phone number.
+	UpdateProfile(ctx context.Context, user models.User) (models.User, error)
+	ListUsersWithRoles(ctx context.Context) ([]models.UserWithRoles, error)
+	DeactivateUser(ctx context.Context, email, role string) error
+	GrantRole(ctx context.Context, email, role string) error
+	RevokeRole(ctx context.Context, email, role string) error
+	ExportAccount(ctx context.Context, userID int) (*models.AccountExport, error)
+	// DeleteAccount anonymises the user; see database.SQLStore.DeleteAccount.
+	DeleteAccount(ctx context.Context, userID int) error
+}
@@ -6,3 +6,7 @@ const UserEmailKey contextKey = "userEmail"
 const UserIDKey contextKey = "userID"
 const UserNameKey contextKey = "userName"
 const UserRoleKey contextKey = "userRole"
+const UserRolesKey contextKey = "userRoles"
+const APIKeyScopesKey contextKey = "apiKeyScopes"
+const SessionIDKey contextKey = "sessionID"
+const ImpersonatorIDKey contextKey = "impersonatorID"
@@ -7,48 +7,219 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("09bc8bd828263e4985faf8d8b6b575071a8aede7efbd8a877ed56997c0ac672a")
+const (
+	AccessTokenTTL    = 15 * time.Minute
+	RefreshTokenTTL   = 7 * 24 * time.Hour
+	ChallengeTokenTTL = 5 * time.Minute
+	ImpersonationTTL  = 30 * time.Minute
+	IdentityLinkTTL   = 10 * time.Minute
+)
+
+const (
+	purposeMFAChallenge = "mfa_challenge"
+	purposeInvitation   = "invitation"
+	purposeIdentityLink = "identity_link"
+)
 
 type Claims struct {
-	UserID int    `json:"user_id"`
-	Email  string `json:"email"`
-	Name   string `json:"name"`
-	Role   string `json:"role"`
+	UserID         int      `json:"user_id"`
+	Email          string   `json:"email"`
+	Name           string   `json:"name"`
+	Role           string   `json:"role"`
+	Roles          []string `json:"roles"`
+	TokenVersion   int      `json:"ver"`
+	SessionID      string   `json:"sid,omitempty"`
+	ImpersonatorID int      `json:"imp,omitempty"`
+	Purpose        string   `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userId int, email, name, role string) (string, error) {
-	expirationTime := time.Now().Add(1 * time.Hour)
+func GenerateJWT(userId int, email, name string, roles []string, tokenVersion int, sessionID string) (string, error) {
+	claims, err := newAccessClaims(userId, email, name, roles, tokenVersion, AccessTokenTTL)
+	if err != nil {
+		return "", err
+	}
+	claims.SessionID = sessionID
+
+	return signToken(claims)
+}
+
+// GenerateImpersonationJWT issues an access token for userId on behalf of an
+// admin. It is not tied to a session and cannot be refreshed.
+func GenerateImpersonationJWT(userId int, email, name string, roles []string, tokenVersion, impersonatorID int) (string, *Claims, error) {
+	claims, err := newAccessClaims(userId, email, name, roles, tokenVersion, ImpersonationTTL)
+	if err != nil {
+		return "", nil, err
+	}
+	claims.ImpersonatorID = impersonatorID
+
+	token, err := signToken(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+func newAccessClaims(userId int, email, name string, roles []string, tokenVersion int, ttl time.Duration) (*Claims, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var role string
+	if len(roles) > 0 {
+		role = roles[0]
+	}
+
+	return &Claims{
+		UserID:       userId,
+		Email:        email,
+		Name:         name,
+		Role:         role,
+		Roles:        roles,
+		TokenVersion: tokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}, nil
+}
+
+func ValidateJWT(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := parseToken(tokenString, claims)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid || claims.Purpose != "" {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// GenerateChallengeToken issues the short-lived token handed out after the
+// password step of a two-factor login. It cannot be used as an access token.
+// Its ID and token version let it be used up and revoked like one.
+func GenerateChallengeToken(userID, tokenVersion int) (string, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 
 	claims := &Claims{
-		UserID: userId,
-		Email:  email,
-		Name:   name,
-		Role:   role,
+		UserID:       userID,
+		TokenVersion: tokenVersion,
+		Purpose:      purposeMFAChallenge,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ChallengeTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	return signToken(claims)
+}
+
+func ValidateChallengeToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := parseToken(tokenString, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid || claims.Purpose != purposeMFAChallenge {
+		return nil, errors.New("invalid challenge token")
+	}
+
+	return claims, nil
+}
+
+type InvitationClaims struct {
+	InvitationID int    `json:"invitation_id"`
+	Role         string `json:"role"`
+	Email        string `json:"email,omitempty"`
+	Purpose      string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+func GenerateInvitationToken(invitationID int, role, email string, expiresAt time.Time) (string, error) {
+	claims := &InvitationClaims{
+		InvitationID: invitationID,
+		Role:         role,
+		Email:        email,
+		Purpose:      purposeInvitation,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return signToken(claims)
+}
+
+func ValidateInvitationToken(tokenString string) (*InvitationClaims, error) {
+	claims := &InvitationClaims{}
+
+	token, err := parseToken(tokenString, claims)
+	if err != nil {
+		return nil, err
+	}
 
-	return token.SignedString(jwtSecret)
+	if !token.Valid || claims.Purpose != purposeInvitation {
+		return nil, errors.New("invalid invitation token")
+	}
+
+	return claims, nil
 }
 
-func ValidateJWT(tokenString string) (*Claims, error) {
-	claims := &Claims{}
+// IdentityLinkClaims name an identity provider account that is not linked to
+// any user yet. The token is handed to the browser after a failed single
+// sign-on so that a signed-in user can attach the identity to their account.
+type IdentityLinkClaims struct {
+	Issuer  string `json:"idp_iss"`
+	Subject string `json:"idp_sub"`
+	Email   string `json:"email,omitempty"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+func GenerateIdentityLinkToken(issuer, subject, email string) (string, error) {
+	now := time.Now()
+
+	claims := &IdentityLinkClaims{
+		Issuer:  issuer,
+		Subject: subject,
+		Email:   email,
+		Purpose: purposeIdentityLink,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(IdentityLinkTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	return signToken(claims)
+}
 
+func ValidateIdentityLinkToken(tokenString string) (*IdentityLinkClaims, error) {
+	claims := &IdentityLinkClaims{}
+
+	token, err := parseToken(tokenString, claims)
 	if err != nil {
 		return nil, err
 	}
 
-	if !token.Valid {
-		return nil, errors.New("invalid token")
+	if !token.Valid || claims.Purpose != purposeIdentityLink || claims.Issuer == "" || claims.Subject == "" {
+		return nil, errors.New("invalid identity link token")
 	}
 
 	return claims, nil
@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const defaultKeyID = "default"
+
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type keyFile struct {
+	Active string `json:"active"`
+	Keys   []struct {
+		ID             string `json:"kid"`
+		Algorithm      string `json:"alg"`
+		Secret         string `json:"secret"`
+		PrivateKeyFile string `json:"private_key_file"`
+		PublicKeyFile  string `json:"public_key_file"`
+	} `json:"keys"`
+}
+
+var (
+	activeKey   *SigningKey
+	signingKeys = map[string]*SigningKey{}
+)
+
+// LoadSigningKeys configures the keys used to sign and verify tokens. When a
+// key file is given it may list several keys so that tokens signed with a
+// retiring key keep validating; keys without private material only verify.
+// Otherwise the HS256 secret is used as the single key.
+func LoadSigningKeys(keysFile, secret string) error {
+	keys := map[string]*SigningKey{}
+	var active *SigningKey
+
+	switch {
+	case keysFile != "":
+		data, err := os.ReadFile(keysFile)
+		if err != nil {
+			return fmt.Errorf("reading key file: %w", err)
+		}
+
+		var cfg keyFile
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return fmt.Errorf("parsing key file: %w", err)
+		}
+
+		baseDir := filepath.Dir(keysFile)
+		for _, k := range cfg.Keys {
+			if k.ID == "" {
+				return errors.New("every key needs a kid")
+			}
+			if _, ok := keys[k.ID]; ok {
+				return fmt.Errorf("duplicate kid %q", k.ID)
+			}
+			key, err := loadKey(baseDir, k.ID, k.Algorithm, k.Secret, k.PrivateKeyFile, k.PublicKeyFile)
+			if err != nil {
+				return fmt.Errorf("key %q: %w", k.ID, err)
+			}
+			keys[k.ID] = key
+		}
+
+		active = keys[cfg.Active]
+		if active == nil {
+			return fmt.Errorf("active key %q is not defined", cfg.Active)
+		}
+	case secret != "":
+		key, err := loadKey("", defaultKeyID, jwt.SigningMethodHS256.Alg(), secret, "", "")
+		if err != nil {
+			return err
+		}
+		active = key
+		keys[active.ID] = active
+	default:
+		return errors.New("no JWT signing key configured")
+	}
+
+	if active.signKey == nil {
+		return fmt.Errorf("active key %q has no private key", active.ID)
+	}
+
+	activeKey = active
+	signingKeys = keys
+	return nil
+}
+
+func loadKey(baseDir, id, alg, secret, privateFile, publicFile string) (*SigningKey, error) {
+	key := &SigningKey{ID: id, Algorithm: alg}
+
+	switch alg {
+	case jwt.SigningMethodHS256.Alg():
+		if len(secret) < 32 {
+			return nil, errors.New("HS256 secret must be at least 32 bytes")
+		}
+		key.signKey = []byte(secret)
+		key.verifyKey = []byte(secret)
+		return key, nil
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg():
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	if privateFile != "" {
+		block, err := readPEM(baseDir, privateFile)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parsing private key: %w", err)
+			}
+		}
+		signer, ok := priv.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("private key cannot sign")
+		}
+		key.signKey = priv
+		key.verifyKey = signer.Public()
+	} else if publicFile != "" {
+		block, err := readPEM(baseDir, publicFile)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing public key: %w", err)
+		}
+		key.verifyKey = pub
+	} else {
+		return nil, errors.New("private_key_file or public_key_file is required")
+	}
+
+	switch key.verifyKey.(type) {
+	case *rsa.PublicKey:
+		if alg != jwt.SigningMethodRS256.Alg() {
+			return nil, fmt.Errorf("RSA key cannot be used with %s", alg)
+		}
+	case ed25519.PublicKey:
+		if alg != jwt.SigningMethodEdDSA.Alg() {
+			return nil, fmt.Errorf("Ed25519 key cannot be used with %s", alg)
+		}
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	return key, nil
+}
+
+func readPEM(baseDir, path string) (*pem.Block, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM block", path)
+	}
+	return block, nil
+}
+
+func signToken(claims jwt.Claims) (string, error) {
+	if activeKey == nil {
+		return "", errors.New("no JWT signing key configured")
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(activeKey.Algorithm), claims)
+	token.Header["kid"] = activeKey.ID
+
+	return token.SignedString(activeKey.signKey)
+}
+
+func parseToken(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := signingKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.verifyKey, nil
+	}, jwt.WithValidMethods([]string{
+		jwt.SigningMethodHS256.Alg(),
+		jwt.SigningMethodRS256.Alg(),
+		jwt.SigningMethodEdDSA.Alg(),
+	}))
+}
+
+// PublicJWKs lists the asymmetric verification keys. HS256 secrets are never
+// published.
+func PublicJWKs() []JWK {
+	jwks := []JWK{}
+	for _, key := range signingKeys {
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Algorithm,
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks = append(jwks, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Algorithm,
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(jwks, func(i, j int) bool { return jwks[i].KeyID < jwks[j].KeyID })
+	return jwks
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultPasswordMinLength = 8
+	// bcrypt ignores everything after 72 bytes, so longer passwords would
+	// silently lose entropy.
+	passwordMaxLength = 72
+)
+
+var ErrWeakPassword = errors.New("password does not meet the policy")
+
+type PasswordPolicy struct {
+	MinLength  int
+	BcryptCost int
+	denylist   map[string]bool
+}
+
+var passwordPolicy = PasswordPolicy{
+	MinLength:  defaultPasswordMinLength,
+	BcryptCost: bcrypt.DefaultCost,
+}
+
+// ConfigurePasswordPolicy sets the rules for new passwords. The denylist file
+// holds one common or breached password per line; blank lines and lines
+// starting with # are ignored. Zero values keep the defaults.
+func ConfigurePasswordPolicy(minLength, bcryptCost int, denylistFile string) error {
+	policy := PasswordPolicy{
+		MinLength:  defaultPasswordMinLength,
+		BcryptCost: bcrypt.DefaultCost,
+	}
+
+	if minLength != 0 {
+		if minLength < 1 || minLength > passwordMaxLength {
+			return fmt.Errorf("minimum password length must be between 1 and %d", passwordMaxLength)
+		}
+		policy.MinLength = minLength
+	}
+
+	if bcryptCost != 0 {
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		policy.BcryptCost = bcryptCost
+	}
+
+	if denylistFile != "" {
+		f, err := os.Open(denylistFile)
+		if err != nil {
+			return fmt.Errorf("opening password denylist: %w", err)
+		}
+		defer f.Close()
+
+		policy.denylist = map[string]bool{}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			policy.denylist[strings.ToLower(line)] = true
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("reading password denylist: %w", err)
+		}
+	}
+
+	passwordPolicy = policy
+	return nil
+}
+
+// CheckPasswordPolicy returns an error wrapping ErrWeakPassword, with a
+// message suitable for the user, when the password is not acceptable.
+func CheckPasswordPolicy(password, email string) error {
+	switch {
+	case len(password) < passwordPolicy.MinLength:
+		return fmt.Errorf("%w: it must be at least %d characters long", ErrWeakPassword, passwordPolicy.MinLength)
+	case len(password) > passwordMaxLength:
+		return fmt.Errorf("%w: it must be at most %d bytes long", ErrWeakPassword, passwordMaxLength)
+	case email != "" && strings.EqualFold(strings.TrimSpace(password), strings.TrimSpace(email)):
+		return fmt.Errorf("%w: it must not be your email address", ErrWeakPassword)
+	case passwordPolicy.denylist[strings.ToLower(password)]:
+		return fmt.Errorf("%w: it is too common, please choose another one", ErrWeakPassword)
+	}
+	return nil
+}
+
+func HashPassword(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), passwordPolicy.BcryptCost)
+}
+
+// PasswordNeedsRehash reports whether a hash was made with a lower cost than
+// the one currently configured.
+func PasswordNeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost < passwordPolicy.BcryptCost
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// The SHA-1 test vectors of RFC 6238, appendix B, cut to our six digits.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestValidateTOTPAcceptsRFC6238Vectors(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		step, ok := ValidateTOTP(rfc6238Secret, v.code, time.Unix(v.unix, 0))
+		if !ok {
+			t.Errorf("ValidateTOTP(%s) at %d rejected", v.code, v.unix)
+			continue
+		}
+		if want := v.unix / totpPeriod; step != want {
+			t.Errorf("ValidateTOTP(%s) at %d: step = %d, want %d", v.code, v.unix, step, want)
+		}
+	}
+}
+
+func TestValidateTOTPAllowsOneStepOfDrift(t *testing.T) {
+	v := rfc6238Vectors[1]
+	for _, drift := range []int64{-totpPeriod, totpPeriod} {
+		if _, ok := ValidateTOTP(rfc6238Secret, v.code, time.Unix(v.unix+drift, 0)); !ok {
+			t.Errorf("code rejected %ds off", drift)
+		}
+	}
+	for _, drift := range []int64{-2 * totpPeriod, 2 * totpPeriod} {
+		if _, ok := ValidateTOTP(rfc6238Secret, v.code, time.Unix(v.unix+drift, 0)); ok {
+			t.Errorf("code accepted %ds off", drift)
+		}
+	}
+}
+
+func TestValidateTOTPRejectsMalformedCodes(t *testing.T) {
+	at := time.Unix(rfc6238Vectors[0].unix, 0)
+	for _, code := range []string{"", "28708", "94287082", "28708x"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, at); ok {
+			t.Errorf("ValidateTOTP(%q) accepted", code)
+		}
+	}
+}